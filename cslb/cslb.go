@@ -82,6 +82,11 @@ func (lb *LoadBalancer) NextFor(input interface{}) (Node, error) {
 	})
 }
 
+// Metrics returns node counters of LoadBalancer, it's nil if MaxNodeFailedRatio is NodeFailedUnlimited
+func (lb *LoadBalancer) Metrics() *Metrics {
+	return lb.metrics
+}
+
 func (lb *LoadBalancer) NodeFailed(node Node) {
 	if lb.metrics == nil {
 		return
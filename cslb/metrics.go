@@ -67,6 +67,18 @@ func (m *Metrics) ResetAllNodes() {
 	m.nodeFailedCounter.Reset()
 }
 
+// Describe implements prometheus.Collector, so node counters could be registered into other registerer
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.nodeCounter.Describe(ch)
+	m.nodeFailedCounter.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.nodeCounter.Collect(ch)
+	m.nodeFailedCounter.Collect(ch)
+}
+
 func getCounterValue(counter prometheus.Counter) (float64, error) {
 	metric := &dto.Metric{}
 	if err := counter.Write(metric); err != nil {
@@ -0,0 +1,159 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/XiaoMi/go-fds/fds/accesslog"
+	"github.com/stretchr/testify/assert"
+)
+
+// format is the layout of lines below, the service doesn't publish one
+var format = accesslog.Format{
+	accesslog.FieldBucket, accesslog.FieldTime, accesslog.FieldRemoteIP, accesslog.FieldRequester,
+	accesslog.FieldRequestID, accesslog.FieldOperation, accesslog.FieldObject, accesslog.FieldRequestURI,
+	accesslog.FieldStatus, accesslog.FieldErrorCode, accesslog.FieldBytesSent, accesslog.FieldObjectSize,
+	accesslog.FieldTotalTime, accesslog.FieldTurnaroundTime, accesslog.FieldReferer, accesslog.FieldUserAgent,
+}
+
+const (
+	line1 = `bucket [19/Oct/2026:08:00:00 +0800] 10.0.0.1 ak1 req-1 GetObject a.txt "GET /bucket/a.txt HTTP/1.1" 200 - 5 5 3 2 "-" "curl/7.0"`
+	line2 = `bucket [19/Oct/2026:08:00:01 +0800] 10.0.0.2 ak2 req-2 GetObject a.txt "GET /bucket/a.txt HTTP/1.1" 403 AccessDenied 0 - 1 - "-" "Go-http-client/1.1"`
+	line3 = `bucket [19/Oct/2026:08:00:02 +0800] 10.0.0.1 ak1 req-3 PutObject b.txt "PUT /bucket/b.txt HTTP/1.1" 200 - - 10 8 6 "https://example.com/" "curl/7.0"`
+)
+
+func TestParse(t *testing.T) {
+	record, err := format.Parse(line1)
+	assert.Nil(t, err)
+	assert.Equal(t, "bucket", record.Bucket)
+	assert.True(t, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC).Equal(record.Time))
+	assert.Equal(t, "10.0.0.1", record.RemoteIP)
+	assert.Equal(t, "ak1", record.Requester)
+	assert.Equal(t, "GetObject", record.Operation)
+	assert.Equal(t, "a.txt", record.Object)
+	assert.Equal(t, "GET /bucket/a.txt HTTP/1.1", record.RequestURI)
+	assert.Equal(t, 200, record.Status)
+	assert.Equal(t, "", record.ErrorCode)
+	assert.Equal(t, int64(5), record.BytesSent)
+	assert.Equal(t, 3*time.Millisecond, record.TotalTime)
+	assert.Equal(t, "", record.Referer)
+	assert.Equal(t, "curl/7.0", record.UserAgent)
+
+	_, err = format.Parse(`bucket [19/Oct/2026:08:00:00 +0800] 10.0.0.1`)
+	assert.True(t, errors.Is(err, accesslog.ErrFieldCount))
+	_, err = format.Parse(`bucket "unterminated`)
+	assert.Equal(t, accesslog.ErrUnterminatedField, err)
+	_, err = format.Parse(`bucket "escaped quote at the end\"`)
+	assert.Equal(t, accesslog.ErrUnterminatedField, err)
+
+	// quotes in user agent are escaped
+	record, err = format.Parse(strings.Replace(line1, `"curl/7.0"`, `"Mozilla/5.0 (\"quoted\" \\ agent)"`, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, `Mozilla/5.0 ("quoted" \ agent)`, record.UserAgent)
+
+	format := accesslog.Format{accesslog.FieldRemoteIP, accesslog.FieldStatus, "region"}
+	record, err = format.Parse(`10.0.0.1 404 cn-beijing`)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, record.Status)
+	assert.Equal(t, map[string]string{"region": "cn-beijing"}, record.Extra)
+}
+
+func TestReader(t *testing.T) {
+	reader := accesslog.NewReader(strings.NewReader(line1+"\n\nmalformed\n"+line2+"\n"), format)
+
+	record, err := reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "req-1", record.RequestID)
+
+	_, err = reader.Next()
+	var parseErr *accesslog.ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 3, parseErr.Line)
+
+	record, err = reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "req-2", record.RequestID)
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func newLogServer(t *testing.T, enabled bool) *fds.Client {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(line3 + "\nmalformed\n"))
+	w.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/bucket":
+			json.NewEncoder(w).Encode(fds.AccessLog{BucketName: "bucket", Enabled: enabled, LogBucketName: "logs", LogPrefix: "bucket/"})
+		case r.URL.Path == "/logs":
+			assert.Equal(t, "bucket/", r.URL.Query().Get("prefix"))
+			w.Write([]byte(`{"name":"logs","objects":[
+				{"name":"bucket/1.log","lastModified":"2026-10-18T00:00:00Z"},
+				{"name":"bucket/2.log","lastModified":"2026-10-19T00:00:00Z"},
+				{"name":"bucket/3.log.gz","lastModified":"2026-10-19T01:00:00Z"}]}`))
+		case r.URL.Path == "/logs/bucket/2.log":
+			w.Write([]byte(line1 + "\n" + line2 + "\n"))
+		case r.URL.Path == "/logs/bucket/3.log.gz":
+			w.Write(gz.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conf, _ := fds.NewClientConfiguration(strings.TrimPrefix(server.URL, "http://"))
+	conf.EnableHTTPS = false
+	return fds.New("ak", "sk", conf)
+}
+
+func TestScan(t *testing.T) {
+	client := newLogServer(t, true)
+	ctx := context.Background()
+	since := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	objects, err := accesslog.List(ctx, client, &accesslog.ListRequest{BucketName: "bucket", Since: since})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(objects))
+	assert.Equal(t, "logs", objects[0].BucketName)
+	assert.Equal(t, "bucket/2.log", objects[0].ObjectName)
+
+	request := &accesslog.ScanRequest{ListRequest: accesslog.ListRequest{BucketName: "bucket", Since: since}}
+	report := accesslog.NewReport()
+	assert.Equal(t, accesslog.ErrNoFormat, accesslog.Scan(ctx, client, request, report.Add))
+
+	request.Format = format
+	var parseErr *accesslog.ParseError
+	assert.True(t, errors.As(accesslog.Scan(ctx, client, request, report.Add), &parseErr))
+
+	request.SkipMalformed = true
+	report = accesslog.NewReport()
+	assert.Nil(t, accesslog.Scan(ctx, client, request, report.Add))
+
+	assert.Equal(t, int64(3), report.Total.Requests)
+	assert.Equal(t, int64(1), report.Total.Errors)
+	assert.Equal(t, int64(5), report.Total.BytesSent)
+	assert.Equal(t, 2*time.Second, report.End.Sub(report.Start))
+
+	top := report.ByObject.Top(1)
+	assert.Equal(t, 1, len(top))
+	assert.Equal(t, "bucket/a.txt", top[0].Key)
+	assert.Equal(t, int64(2), top[0].Requests)
+	assert.Equal(t, int64(2), report.ByIP["10.0.0.1"].Requests)
+	assert.Equal(t, int64(1), report.ByRequester["ak2"].Errors)
+	assert.Equal(t, int64(1), report.ByStatus["403"].Requests)
+
+	_, err = accesslog.List(ctx, newLogServer(t, false), &accesslog.ListRequest{BucketName: "bucket"})
+	assert.Equal(t, accesslog.ErrAccessLogDisabled, err)
+}
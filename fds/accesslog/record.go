@@ -0,0 +1,262 @@
+// Package accesslog reads access logs which FDS writes into LogBucketName/LogPrefix
+// configured by SetAccessLog, parses records and aggregates them into reports.
+//
+// A record is a line of space separated fields, fields containing spaces are quoted
+// by "" or [], \" is a quote inside "", and - means the field is empty.
+//
+// FDS doesn't publish a specification of the record layout, so this package doesn't
+// ship a default Format. Callers build a Format from the field order of logs written
+// into their own bucket, e.g. Format{FieldBucket, FieldTime, FieldRemoteIP, ...}.
+package accesslog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors of parsing
+var (
+	ErrUnterminatedField = errors.New("accesslog: unterminated quoted field")
+	ErrFieldCount        = errors.New("accesslog: field count doesn't match format")
+	ErrNoFormat          = errors.New("accesslog: format is required")
+)
+
+// TimeLayout is layout of time field
+const TimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Names of fields in Format
+const (
+	FieldBucket         = "bucket"
+	FieldTime           = "time"
+	FieldRemoteIP       = "remote_ip"
+	FieldRequester      = "requester"
+	FieldRequestID      = "request_id"
+	FieldOperation      = "operation"
+	FieldObject         = "object"
+	FieldRequestURI     = "request_uri"
+	FieldStatus         = "status"
+	FieldErrorCode      = "error_code"
+	FieldBytesSent      = "bytes_sent"
+	FieldObjectSize     = "object_size"
+	FieldTotalTime      = "total_time"
+	FieldTurnaroundTime = "turnaround_time"
+	FieldReferer        = "referer"
+	FieldUserAgent      = "user_agent"
+)
+
+// Format is the ordered field names of a record, unknown names are put into Record.Extra
+type Format []string
+
+// Record is a parsed access log record, TotalTime and TurnaroundTime are logged in milliseconds
+type Record struct {
+	Bucket         string
+	Time           time.Time
+	RemoteIP       string
+	Requester      string
+	RequestID      string
+	Operation      string
+	Object         string
+	RequestURI     string
+	Status         int
+	ErrorCode      string
+	BytesSent      int64
+	ObjectSize     int64
+	TotalTime      time.Duration
+	TurnaroundTime time.Duration
+	Referer        string
+	UserAgent      string
+	Extra          map[string]string
+}
+
+// Parse parses line by format f
+func (f Format) Parse(line string) (*Record, error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != len(f) {
+		return nil, fmt.Errorf("%w: %d fields, expect %d", ErrFieldCount, len(fields), len(f))
+	}
+
+	record := &Record{}
+	for i, name := range f {
+		if err := record.set(name, fields[i]); err != nil {
+			return nil, fmt.Errorf("accesslog: field %s: %v", name, err)
+		}
+	}
+	return record, nil
+}
+
+func (record *Record) set(name, value string) error {
+	if value == "-" {
+		return nil
+	}
+
+	var err error
+	switch name {
+	case FieldBucket:
+		record.Bucket = value
+	case FieldTime:
+		record.Time, err = time.Parse(TimeLayout, value)
+	case FieldRemoteIP:
+		record.RemoteIP = value
+	case FieldRequester:
+		record.Requester = value
+	case FieldRequestID:
+		record.RequestID = value
+	case FieldOperation:
+		record.Operation = value
+	case FieldObject:
+		record.Object = value
+	case FieldRequestURI:
+		record.RequestURI = value
+	case FieldStatus:
+		record.Status, err = strconv.Atoi(value)
+	case FieldErrorCode:
+		record.ErrorCode = value
+	case FieldBytesSent:
+		record.BytesSent, err = strconv.ParseInt(value, 10, 64)
+	case FieldObjectSize:
+		record.ObjectSize, err = strconv.ParseInt(value, 10, 64)
+	case FieldTotalTime:
+		record.TotalTime, err = parseMillis(value)
+	case FieldTurnaroundTime:
+		record.TurnaroundTime, err = parseMillis(value)
+	case FieldReferer:
+		record.Referer = value
+	case FieldUserAgent:
+		record.UserAgent = value
+	default:
+		if record.Extra == nil {
+			record.Extra = make(map[string]string)
+		}
+		record.Extra[name] = value
+	}
+	return err
+}
+
+func parseMillis(s string) (time.Duration, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return time.Duration(n) * time.Millisecond, err
+}
+
+// splitFields splits line by spaces, "..." and [...] are single fields without the quotes,
+// \" and \\ in "..." are unescaped
+func splitFields(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return fields, nil
+		}
+
+		var end byte
+		switch line[0] {
+		case '"':
+			end = '"'
+		case '[':
+			end = ']'
+		}
+		if end == 0 {
+			i := strings.IndexAny(line, " \t")
+			if i < 0 {
+				i = len(line)
+			}
+			fields = append(fields, line[:i])
+			line = line[i:]
+			continue
+		}
+
+		if end == '"' {
+			field, n, err := unquoteField(line)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+			line = line[n:]
+			continue
+		}
+
+		i := strings.IndexByte(line[1:], end)
+		if i < 0 {
+			return nil, ErrUnterminatedField
+		}
+		fields = append(fields, line[1:i+1])
+		line = line[i+2:]
+	}
+}
+
+// unquoteField reads the "..." field at the start of line, and returns it with length of line it takes
+func unquoteField(line string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\'):
+			i++
+			b.WriteByte(line[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, ErrUnterminatedField
+}
+
+// ParseError is a line failed to be parsed by Reader
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("accesslog: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads records from a stream of log lines
+type Reader struct {
+	Format Format
+
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader returns a Reader of r parsing records by format
+func NewReader(r io.Reader, format Format) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Reader{Format: format, scanner: scanner}
+}
+
+// Next returns the next record, blank lines are skipped and io.EOF is returned at the end.
+// A *ParseError is returned for a malformed line, and reading can go on after it.
+func (reader *Reader) Next() (*Record, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+		line := strings.TrimSpace(reader.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		record, err := reader.Format.Parse(line)
+		if err != nil {
+			return nil, &ParseError{Line: reader.line, Err: err}
+		}
+		return record, nil
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,107 @@
+package accesslog
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Stats sums records of a key, Errors counts records with status 400 or above
+type Stats struct {
+	Requests  int64
+	Errors    int64
+	BytesSent int64
+	TotalTime time.Duration
+}
+
+func (stats *Stats) add(record *Record) {
+	stats.Requests++
+	if record.Status >= 400 {
+		stats.Errors++
+	}
+	stats.BytesSent += record.BytesSent
+	stats.TotalTime += record.TotalTime
+}
+
+// KeyStats is Stats of a key
+type KeyStats struct {
+	Key string
+	Stats
+}
+
+// StatsMap is Stats by key
+type StatsMap map[string]*Stats
+
+func (m StatsMap) add(key string, record *Record) {
+	stats, ok := m[key]
+	if !ok {
+		stats = &Stats{}
+		m[key] = stats
+	}
+	stats.add(record)
+}
+
+// Top returns n keys with most requests, ties are ordered by key, all keys are returned if n <= 0
+func (m StatsMap) Top(n int) []KeyStats {
+	result := make([]KeyStats, 0, len(m))
+	for k, v := range m {
+		result = append(result, KeyStats{Key: k, Stats: *v})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Requests != result[j].Requests {
+			return result[i].Requests > result[j].Requests
+		}
+		return result[i].Key < result[j].Key
+	})
+	if n > 0 && n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+// Report aggregates records per object, client IP, requester and status, keys of
+// ByStatus are status codes in decimal, e.g. "404"
+type Report struct {
+	Total       Stats
+	Start       time.Time
+	End         time.Time
+	ByObject    StatsMap
+	ByIP        StatsMap
+	ByRequester StatsMap
+	ByStatus    StatsMap
+}
+
+// NewReport returns an empty report
+func NewReport() *Report {
+	return &Report{
+		ByObject:    StatsMap{},
+		ByIP:        StatsMap{},
+		ByRequester: StatsMap{},
+		ByStatus:    StatsMap{},
+	}
+}
+
+// Add adds record into report, it can be used as fn of Scan
+func (report *Report) Add(record *Record) error {
+	report.Total.add(record)
+	if !record.Time.IsZero() {
+		if report.Start.IsZero() || record.Time.Before(report.Start) {
+			report.Start = record.Time
+		}
+		if record.Time.After(report.End) {
+			report.End = record.Time
+		}
+	}
+
+	if record.Object != "" {
+		report.ByObject.add(record.Bucket+"/"+record.Object, record)
+	}
+	if record.RemoteIP != "" {
+		report.ByIP.add(record.RemoteIP, record)
+	}
+	if record.Requester != "" {
+		report.ByRequester.add(record.Requester, record)
+	}
+	report.ByStatus.add(strconv.Itoa(record.Status), record)
+	return nil
+}
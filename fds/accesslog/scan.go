@@ -0,0 +1,134 @@
+package accesslog
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds"
+)
+
+// ErrAccessLogDisabled is returned if access log of bucket is not enabled
+var ErrAccessLogDisabled = errors.New("accesslog: access log of bucket is not enabled")
+
+// LogObject is a log object written for a bucket
+type LogObject struct {
+	BucketName string
+	fds.ObjectSummary
+}
+
+// ListRequest is input of List and Scan
+type ListRequest struct {
+	// BucketName is the bucket whose access logs are read, its GetAccessLog
+	// settings tell where logs are
+	BucketName string
+	// Since and Until limit log objects by LastModified, zero means no limit
+	Since time.Time
+	Until time.Time
+}
+
+// List returns log objects of bucket in the order of listing
+func List(ctx context.Context, client *fds.Client, request *ListRequest) ([]LogObject, error) {
+	settings, err := client.GetAccessLogWithContext(ctx, request.BucketName)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled || settings.LogBucketName == "" {
+		return nil, ErrAccessLogDisabled
+	}
+
+	var objects []LogObject
+	listing, err := client.ListObjectsWithContext(ctx, &fds.ListObjectsRequest{
+		BucketName: settings.LogBucketName,
+		Prefix:     settings.LogPrefix,
+	})
+	for {
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range listing.ObjectSummaries {
+			if !request.Since.IsZero() && object.LastModified.Before(request.Since) {
+				continue
+			}
+			if !request.Until.IsZero() && !object.LastModified.Before(request.Until) {
+				continue
+			}
+			objects = append(objects, LogObject{BucketName: settings.LogBucketName, ObjectSummary: object})
+		}
+		if !listing.Truncated {
+			return objects, nil
+		}
+		listing, err = client.ListObjectsNextBatchWithContext(ctx, listing)
+	}
+}
+
+// ScanRequest is input of Scan
+type ScanRequest struct {
+	ListRequest
+	// Format of records, it's required
+	Format Format
+	// SkipMalformed skips lines failed to be parsed instead of stopping
+	SkipMalformed bool
+}
+
+// Scan streams records of all log objects of bucket to fn, objects ending with .gz
+// are decompressed. Scanning stops at the first error returned by fn.
+func Scan(ctx context.Context, client *fds.Client, request *ScanRequest, fn func(*Record) error) error {
+	if len(request.Format) == 0 {
+		return ErrNoFormat
+	}
+
+	objects, err := List(ctx, client, &request.ListRequest)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		if err := scanObject(ctx, client, request, object, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanObject(ctx context.Context, client *fds.Client, request *ScanRequest, object LogObject, fn func(*Record) error) error {
+	body, err := client.GetObjectWithContext(ctx, &fds.GetObjectRequest{
+		BucketName: object.BucketName,
+		ObjectName: object.ObjectName,
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var r io.Reader = body
+	if strings.HasSuffix(object.ObjectName, ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := NewReader(r, request.Format)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) && request.SkipMalformed {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
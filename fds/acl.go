@@ -1,5 +1,12 @@
 package fds
 
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+)
+
 type aclOption struct {
 	ACL string `param:"acl" header:"-"`
 }
@@ -48,3 +55,381 @@ type AccessControlList struct {
 func (acl *AccessControlList) AddGrant(grant Grant) {
 	acl.Grants = append(acl.Grants, grant)
 }
+
+// Predefined group grantees
+var (
+	AllUsers           = GrantKey{ID: "ALL_USERS"}
+	AuthenticatedUsers = GrantKey{ID: "AUTHENTICATED_USERS"}
+)
+
+// NewUserGrant returns a grant of perm to user id
+func NewUserGrant(id string, perm GrantPermission) Grant {
+	return Grant{Grantee: GrantKey{ID: id}, Permission: perm, Type: GrantTypeUser}
+}
+
+// NewGroupGrant returns a grant of perm to group grantee, e.g. AllUsers
+func NewGroupGrant(grantee GrantKey, perm GrantPermission) Grant {
+	return Grant{Grantee: grantee, Permission: perm, Type: GrantTypeGroup}
+}
+
+// same tells whether g and other grant the same permission to the same grantee,
+// DisplayName is ignored
+func (g Grant) same(other Grant) bool {
+	return g.Grantee.ID == other.Grantee.ID && g.Type == other.Type && g.Permission == other.Permission
+}
+
+// appliesTo tells whether g applies to grantee, including grants to predefined groups
+func (g Grant) appliesTo(grantee GrantKey) bool {
+	switch g.Grantee.ID {
+	case grantee.ID, AllUsers.ID:
+		return true
+	case AuthenticatedUsers.ID:
+		return grantee.ID != AllUsers.ID
+	}
+	return false
+}
+
+func (acl *AccessControlList) index(grant Grant) int {
+	for i, g := range acl.Grants {
+		if g.same(grant) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Grant adds grant into ACL if there isn't the same one
+func (acl *AccessControlList) Grant(grant Grant) *AccessControlList {
+	if acl.index(grant) < 0 {
+		acl.AddGrant(grant)
+	}
+	return acl
+}
+
+// Revoke removes grant from ACL
+func (acl *AccessControlList) Revoke(grant Grant) *AccessControlList {
+	grants := acl.Grants[:0]
+	for _, g := range acl.Grants {
+		if !g.same(grant) {
+			grants = append(grants, g)
+		}
+	}
+	acl.Grants = grants
+	return acl
+}
+
+// RevokeAll removes all grants of grantee from ACL
+func (acl *AccessControlList) RevokeAll(grantee GrantKey) *AccessControlList {
+	grants := acl.Grants[:0]
+	for _, g := range acl.Grants {
+		if g.Grantee.ID != grantee.ID {
+			grants = append(grants, g)
+		}
+	}
+	acl.Grants = grants
+	return acl
+}
+
+// HasPermission tells whether grantee has perm, FULL_CONTROL implies all permissions.
+// Grants to AllUsers apply to everyone, and grants to AuthenticatedUsers apply to everyone
+// but AllUsers, which stands for anonymous requests.
+func (acl *AccessControlList) HasPermission(grantee GrantKey, perm GrantPermission) bool {
+	if acl == nil {
+		return false
+	}
+	for _, g := range acl.Grants {
+		if !g.appliesTo(grantee) {
+			continue
+		}
+		if g.Permission == perm || g.Permission == GrantPermissionFullControl {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPublic tells whether anyone can read without authentication
+func (acl *AccessControlList) IsPublic() bool {
+	return acl.HasPermission(AllUsers, GrantPermissionRead)
+}
+
+// MakePublic grants READ to AllUsers
+func (acl *AccessControlList) MakePublic() *AccessControlList {
+	return acl.Grant(NewGroupGrant(AllUsers, GrantPermissionRead))
+}
+
+// MakePrivate removes all grants to AllUsers and AuthenticatedUsers
+func (acl *AccessControlList) MakePrivate() *AccessControlList {
+	return acl.RevokeAll(AllUsers).RevokeAll(AuthenticatedUsers)
+}
+
+// Clone returns a deep copy of ACL
+func (acl *AccessControlList) Clone() *AccessControlList {
+	return &AccessControlList{
+		Grants: append([]Grant(nil), acl.Grants...),
+		Owner:  acl.Owner,
+	}
+}
+
+// ACLDiff is difference between current and desired ACL
+type ACLDiff struct {
+	Added   []Grant
+	Removed []Grant
+}
+
+// Empty tells whether nothing needs to be changed
+func (diff *ACLDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0
+}
+
+// isOwnerGrant tells whether g is granted to owner of acl, such grants are never removed
+// since losing them locks the owner out
+func (acl *AccessControlList) isOwnerGrant(g Grant) bool {
+	return acl.Owner.ID != "" && g.Type == GrantTypeUser && g.Grantee.ID == acl.Owner.ID
+}
+
+// DiffACL returns grants to add and to remove for turning current into desired, Owner of desired
+// is ignored and grants to Owner of current are never removed. A nil ACL is taken as empty.
+func DiffACL(current, desired *AccessControlList) *ACLDiff {
+	if current == nil {
+		current = &AccessControlList{}
+	}
+	if desired == nil {
+		desired = &AccessControlList{}
+	}
+
+	diff := &ACLDiff{}
+	for _, g := range desired.Grants {
+		if current.index(g) < 0 && (&AccessControlList{Grants: diff.Added}).index(g) < 0 {
+			diff.Added = append(diff.Added, g)
+		}
+	}
+	for _, g := range current.Grants {
+		if current.isOwnerGrant(g) {
+			continue
+		}
+		if desired.index(g) < 0 && (&AccessControlList{Grants: diff.Removed}).index(g) < 0 {
+			diff.Removed = append(diff.Removed, g)
+		}
+	}
+	return diff
+}
+
+type deleteACLOption struct {
+	ACL    string `param:"acl" header:"-"`
+	Action string `param:"action" header:"-"`
+}
+
+// DeleteBucketACL removes grants of acl from bucket, SetBucketACL only adds grants
+func (client *Client) DeleteBucketACL(bucketName string, acl *AccessControlList) error {
+	return client.DeleteBucketACLWithContext(context.Background(), bucketName, acl)
+}
+
+// DeleteBucketACLWithContext removes grants of acl from bucket with context controlling
+func (client *Client) DeleteBucketACLWithContext(ctx context.Context, bucketName string, acl *AccessControlList) error {
+	aclBytes, e := json.Marshal(acl)
+	if e != nil {
+		return errors.New("fds client: can't marshal acl")
+	}
+
+	req := &clientRequest{
+		BucketName:         bucketName,
+		Method:             HTTPPut,
+		QueryHeaderOptions: deleteACLOption{Action: "delete"},
+		Data:               bytes.NewReader(aclBytes),
+		Operation:          "DeleteBucketACL",
+	}
+
+	resp, err := client.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return err
+}
+
+// DeleteObjectACLRequest is input of DeleteObjectACL
+type DeleteObjectACLRequest struct {
+	deleteACLOption
+	BucketName string             `param:"-" header:"-"`
+	ObjectName string             `param:"-" header:"-"`
+	VersionID  string             `param:"versionId,omitempty" header:"-"`
+	ACL        *AccessControlList `param:"-" header:"-"`
+}
+
+// DeleteObjectACL removes grants of ACL from object, SetObjectACL only adds grants
+func (client *Client) DeleteObjectACL(request *DeleteObjectACLRequest) error {
+	return client.DeleteObjectACLWithContext(context.Background(), request)
+}
+
+// DeleteObjectACLWithContext removes grants of ACL from object with context controlling
+func (client *Client) DeleteObjectACLWithContext(ctx context.Context, request *DeleteObjectACLRequest) error {
+	aclBytes, e := json.Marshal(request.ACL)
+	if e != nil {
+		return errors.New("fds client: can't marshal acl")
+	}
+
+	request.deleteACLOption.Action = "delete"
+	req := &clientRequest{
+		BucketName:         request.BucketName,
+		ObjectName:         request.ObjectName,
+		Method:             HTTPPut,
+		QueryHeaderOptions: request,
+		Data:               bytes.NewReader(aclBytes),
+		Operation:          "DeleteObjectACL",
+	}
+
+	resp, err := client.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return err
+}
+
+// SetObjectPrivate is a shortcut of removing grants to AllUsers and AuthenticatedUsers from object
+func (client *Client) SetObjectPrivate(bucketName, objectName string) error {
+	return client.SetObjectPrivateWithContext(context.Background(), bucketName, objectName)
+}
+
+// SetObjectPrivateWithContext is a shortcut of setting object private with context controlling
+func (client *Client) SetObjectPrivateWithContext(ctx context.Context, bucketName, objectName string) error {
+	_, err := client.UpdateObjectACLWithContext(ctx, &UpdateObjectACLRequest{
+		BucketName: bucketName,
+		ObjectName: objectName,
+		Update:     (*AccessControlList).MakePrivate,
+	})
+	return err
+}
+
+// applyACLDiff adds then removes grants of diff by set and remove
+func applyACLDiff(diff *ACLDiff, set, remove func(*AccessControlList) error) error {
+	if len(diff.Added) > 0 {
+		if err := set(&AccessControlList{Grants: diff.Added}); err != nil {
+			return err
+		}
+	}
+	if len(diff.Removed) > 0 {
+		return remove(&AccessControlList{Grants: diff.Removed})
+	}
+	return nil
+}
+
+// ReconcileBucketACL makes ACL of bucket match desired, only grants in the returned diff
+// are sent and nothing is sent if the diff is empty
+func (client *Client) ReconcileBucketACL(bucketName string, desired *AccessControlList) (*ACLDiff, error) {
+	return client.ReconcileBucketACLWithContext(context.Background(), bucketName, desired)
+}
+
+// ReconcileBucketACLWithContext makes ACL of bucket match desired with context controlling
+func (client *Client) ReconcileBucketACLWithContext(ctx context.Context, bucketName string, desired *AccessControlList) (*ACLDiff, error) {
+	if desired == nil {
+		return nil, ErrorNilACL
+	}
+
+	current, err := client.GetBucketACLWithContext(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffACL(current, desired)
+	err = applyACLDiff(diff, func(acl *AccessControlList) error {
+		return client.SetBucketACLWithContext(ctx, bucketName, acl)
+	}, func(acl *AccessControlList) error {
+		return client.DeleteBucketACLWithContext(ctx, bucketName, acl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// UpdateObjectACLRequest is input of UpdateObjectACL
+type UpdateObjectACLRequest struct {
+	BucketName string
+	ObjectName string
+	VersionID  string
+	// Update turns a copy of current ACL into the desired one, it must not return nil
+	Update func(acl *AccessControlList) *AccessControlList
+	// DryRun only computes the diff
+	DryRun bool
+}
+
+// UpdateObjectACL reads ACL of object, changes it by Update and sends only the difference
+func (client *Client) UpdateObjectACL(request *UpdateObjectACLRequest) (*ACLDiff, error) {
+	return client.UpdateObjectACLWithContext(context.Background(), request)
+}
+
+// UpdateObjectACLWithContext changes ACL of object by Update with context controlling
+func (client *Client) UpdateObjectACLWithContext(ctx context.Context, request *UpdateObjectACLRequest) (*ACLDiff, error) {
+	current, err := client.GetObjectACLWithContext(ctx, &GetObjectACLRequest{
+		BucketName: request.BucketName,
+		ObjectName: request.ObjectName,
+		VersionID:  request.VersionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	desired := request.Update(current.Clone())
+	if desired == nil {
+		return nil, ErrorNilACL
+	}
+
+	diff := DiffACL(current, desired)
+	if request.DryRun {
+		return diff, nil
+	}
+
+	err = applyACLDiff(diff, func(acl *AccessControlList) error {
+		return client.SetObjectACLWithContext(ctx, &SetObjectACLRequest{
+			BucketName: request.BucketName,
+			ObjectName: request.ObjectName,
+			VersionID:  request.VersionID,
+			ACL:        acl,
+		})
+	}, func(acl *AccessControlList) error {
+		return client.DeleteObjectACLWithContext(ctx, &DeleteObjectACLRequest{
+			BucketName: request.BucketName,
+			ObjectName: request.ObjectName,
+			VersionID:  request.VersionID,
+			ACL:        acl,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// ReconcileObjectACLRequest is input of ReconcileObjectACL
+type ReconcileObjectACLRequest struct {
+	BucketName string
+	ObjectName string
+	VersionID  string
+	Desired    *AccessControlList
+}
+
+// ReconcileObjectACL makes ACL of object match Desired, only grants in the returned diff
+// are sent and nothing is sent if the diff is empty
+func (client *Client) ReconcileObjectACL(request *ReconcileObjectACLRequest) (*ACLDiff, error) {
+	return client.ReconcileObjectACLWithContext(context.Background(), request)
+}
+
+// ReconcileObjectACLWithContext makes ACL of object match Desired with context controlling
+func (client *Client) ReconcileObjectACLWithContext(ctx context.Context, request *ReconcileObjectACLRequest) (*ACLDiff, error) {
+	if request.Desired == nil {
+		return nil, ErrorNilACL
+	}
+
+	return client.UpdateObjectACLWithContext(ctx, &UpdateObjectACLRequest{
+		BucketName: request.BucketName,
+		ObjectName: request.ObjectName,
+		VersionID:  request.VersionID,
+		Update: func(*AccessControlList) *AccessControlList {
+			return request.Desired
+		},
+	})
+}
@@ -0,0 +1,166 @@
+package fds
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessControlList_Helpers(t *testing.T) {
+	alice := GrantKey{ID: "alice"}
+	acl := &AccessControlList{}
+	acl.Grant(NewUserGrant("alice", GrantPermissionRead)).Grant(NewUserGrant("alice", GrantPermissionRead))
+	assert.Equal(t, 1, len(acl.Grants))
+
+	assert.True(t, acl.HasPermission(alice, GrantPermissionRead))
+	assert.False(t, acl.HasPermission(alice, GrantPermissionWrite))
+	acl.Grant(NewUserGrant("alice", GrantPermissionFullControl))
+	assert.True(t, acl.HasPermission(alice, GrantPermissionWrite))
+	acl.Revoke(NewUserGrant("alice", GrantPermissionFullControl))
+	assert.False(t, acl.HasPermission(alice, GrantPermissionWrite))
+
+	assert.False(t, acl.IsPublic())
+	acl.MakePublic()
+	assert.True(t, acl.IsPublic())
+	assert.True(t, acl.HasPermission(GrantKey{ID: "bob"}, GrantPermissionRead))
+	acl.Grant(NewGroupGrant(AuthenticatedUsers, GrantPermissionWrite))
+	assert.True(t, acl.HasPermission(GrantKey{ID: "bob"}, GrantPermissionWrite))
+	assert.True(t, acl.HasPermission(AuthenticatedUsers, GrantPermissionWrite))
+	assert.False(t, acl.HasPermission(AllUsers, GrantPermissionWrite))
+	acl.MakePrivate()
+	assert.False(t, acl.IsPublic())
+	assert.Equal(t, []Grant{NewUserGrant("alice", GrantPermissionRead)}, acl.Grants)
+
+	acl.RevokeAll(alice)
+	assert.Empty(t, acl.Grants)
+	assert.False(t, (*AccessControlList)(nil).HasPermission(alice, GrantPermissionRead))
+}
+
+func TestDiffACL(t *testing.T) {
+	current := &AccessControlList{Grants: []Grant{
+		NewUserGrant("alice", GrantPermissionRead),
+		NewGroupGrant(AllUsers, GrantPermissionRead),
+	}}
+	desired := &AccessControlList{Grants: []Grant{
+		{Grantee: GrantKey{ID: "alice", DisplayName: "Alice"}, Permission: GrantPermissionRead, Type: GrantTypeUser},
+		NewUserGrant("bob", GrantPermissionWrite),
+		NewUserGrant("bob", GrantPermissionWrite),
+	}}
+
+	diff := DiffACL(current, desired)
+	assert.Equal(t, []Grant{NewUserGrant("bob", GrantPermissionWrite)}, diff.Added)
+	assert.Equal(t, []Grant{NewGroupGrant(AllUsers, GrantPermissionRead)}, diff.Removed)
+	assert.True(t, DiffACL(current, current).Empty())
+	assert.Equal(t, current.Grants, DiffACL(nil, current).Added)
+
+	// grants to owner are kept even if desired doesn't have them
+	current.Owner = Owner{ID: "alice"}
+	diff = DiffACL(current, &AccessControlList{})
+	assert.Equal(t, []Grant{NewGroupGrant(AllUsers, GrantPermissionRead)}, diff.Removed)
+	assert.Equal(t, diff, DiffACL(current, nil))
+}
+
+// aclServer keeps ACL in memory, PUT adds grants and PUT with action=delete removes them
+type aclServer struct {
+	mu       sync.Mutex
+	acl      AccessControlList
+	requests []string
+}
+
+func (s *aclServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Query().Get("action"))
+	if r.Method == http.MethodGet {
+		json.NewEncoder(w).Encode(s.acl)
+		return
+	}
+
+	acl := &AccessControlList{}
+	json.NewDecoder(r.Body).Decode(acl)
+	for _, g := range acl.Grants {
+		if r.URL.Query().Get("action") == "delete" {
+			s.acl.Revoke(g)
+		} else {
+			s.acl.Grant(g)
+		}
+	}
+}
+
+func TestReconcileBucketACL(t *testing.T) {
+	server := &aclServer{acl: AccessControlList{Grants: []Grant{
+		NewUserGrant("alice", GrantPermissionFullControl),
+		NewGroupGrant(AllUsers, GrantPermissionRead),
+	}}}
+	client := newTestClient(t, server.handle)
+
+	desired := &AccessControlList{Grants: []Grant{
+		NewUserGrant("alice", GrantPermissionFullControl),
+		NewUserGrant("bob", GrantPermissionRead),
+	}}
+	diff, err := client.ReconcileBucketACL("bucket", desired)
+	assert.Nil(t, err)
+	assert.Equal(t, []Grant{NewUserGrant("bob", GrantPermissionRead)}, diff.Added)
+	assert.Equal(t, []Grant{NewGroupGrant(AllUsers, GrantPermissionRead)}, diff.Removed)
+	assert.Equal(t, []string{"GET ", "PUT ", "PUT delete"}, server.requests)
+	assert.True(t, DiffACL(&server.acl, desired).Empty())
+
+	server.requests = nil
+	diff, err = client.ReconcileBucketACL("bucket", desired)
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+	assert.Equal(t, []string{"GET "}, server.requests)
+
+	server.requests = nil
+	_, err = client.ReconcileBucketACL("bucket", nil)
+	assert.Equal(t, ErrorNilACL, err)
+	assert.Nil(t, server.requests)
+}
+
+func TestReconcileObjectACL_Owner(t *testing.T) {
+	server := &aclServer{acl: AccessControlList{
+		Owner: Owner{ID: "alice"},
+		Grants: []Grant{
+			NewUserGrant("alice", GrantPermissionFullControl),
+			NewUserGrant("bob", GrantPermissionRead),
+		},
+	}}
+	client := newTestClient(t, server.handle)
+
+	diff, err := client.ReconcileObjectACL(&ReconcileObjectACLRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Desired:    &AccessControlList{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Grant{NewUserGrant("bob", GrantPermissionRead)}, diff.Removed)
+	assert.Equal(t, []Grant{NewUserGrant("alice", GrantPermissionFullControl)}, server.acl.Grants)
+
+	_, err = client.ReconcileObjectACL(&ReconcileObjectACLRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Equal(t, ErrorNilACL, err)
+	_, err = client.UpdateObjectACL(&UpdateObjectACLRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Update:     func(*AccessControlList) *AccessControlList { return nil },
+	})
+	assert.Equal(t, ErrorNilACL, err)
+	assert.Equal(t, []Grant{NewUserGrant("alice", GrantPermissionFullControl)}, server.acl.Grants)
+}
+
+func TestSetObjectPrivate(t *testing.T) {
+	server := &aclServer{acl: AccessControlList{Grants: []Grant{
+		NewUserGrant("alice", GrantPermissionFullControl),
+	}}}
+	client := newTestClient(t, server.handle)
+
+	assert.Nil(t, client.SetObjectPublic("bucket", "object"))
+	assert.True(t, server.acl.IsPublic())
+
+	assert.Nil(t, client.SetObjectPrivate("bucket", "object"))
+	assert.False(t, server.acl.IsPublic())
+	assert.Equal(t, []Grant{NewUserGrant("alice", GrantPermissionFullControl)}, server.acl.Grants)
+}
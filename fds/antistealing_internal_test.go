@@ -0,0 +1,34 @@
+package fds
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampAntiStealingLinkConfig(t *testing.T) {
+	var stored *TimestampAntiStealingLinkConfig
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bucket", r.URL.Path)
+		assert.Contains(t, r.URL.Query(), "timestampAntiStealingLink")
+		switch r.Method {
+		case http.MethodPut:
+			stored = &TimestampAntiStealingLinkConfig{}
+			json.NewDecoder(r.Body).Decode(stored)
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(stored)
+		case http.MethodDelete:
+			stored = nil
+		}
+	})
+
+	config := &TimestampAntiStealingLinkConfig{Enabled: true, PrimaryKey: "primary", SecondaryKey: "secondary"}
+	assert.Nil(t, client.SetTimestampAntiStealingLinkConfig("bucket", config))
+	got, err := client.GetTimestampAntiStealingLinkConfig("bucket")
+	assert.Nil(t, err)
+	assert.Equal(t, config, got)
+	assert.Nil(t, client.DeleteTimestampAntiStealingLinkConfig("bucket"))
+	assert.Nil(t, stored)
+}
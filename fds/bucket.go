@@ -30,6 +30,7 @@ func (client *Client) CreateBucketWithContext(ctx context.Context, request *Crea
 		Method:             HTTPPut,
 		Data:               buf,
 		QueryHeaderOptions: request,
+		Operation:          "CreateBucket",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -51,6 +52,7 @@ func (client *Client) DoesBucketExitsWithContext(ctx context.Context, bucketName
 	req := &clientRequest{
 		BucketName: bucketName,
 		Method:     HTTPHead,
+		Operation:  "DoesBucketExits",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -76,6 +78,7 @@ func (client *Client) DeleteBucketWithContext(ctx context.Context, bucketName st
 	req := &clientRequest{
 		BucketName: bucketName,
 		Method:     HTTPDelete,
+		Operation:  "DeleteBucket",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -108,6 +111,7 @@ func (client *Client) GetBucketInfoWithContext(ctx context.Context, bucketName s
 		BucketName: bucketName,
 		Method:     HTTPGet,
 		Result:     result,
+		Operation:  "GetBucketInfo",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -140,8 +144,9 @@ func (client *Client) ListBuckets() (*ListBucketsResponse, error) {
 func (client *Client) ListBucketsWithContext(ctx context.Context) (*ListBucketsResponse, error) {
 	result := &ListBucketsResponse{}
 	req := &clientRequest{
-		Method: HTTPGet,
-		Result: result,
+		Method:    HTTPGet,
+		Result:    result,
+		Operation: "ListBuckets",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -169,6 +174,7 @@ func (client *Client) ListAuthorizedBucketsWithContext(ctx context.Context) (*Li
 		Method:             HTTPGet,
 		QueryHeaderOptions: listAuthorizedBucketsOption{""},
 		Result:             result,
+		Operation:          "ListAuthorizedBuckets",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -206,6 +212,7 @@ func (client *Client) MigrateBucketWithContext(ctx context.Context, request *Mig
 		Method:             HTTPPut,
 		QueryHeaderOptions: request,
 		Data:               buf,
+		Operation:          "MigrateBucket",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -229,6 +236,7 @@ func (client *Client) GetBucketACLWithContext(ctx context.Context, bucketName st
 		Method:             HTTPGet,
 		QueryHeaderOptions: aclOption{""},
 		Result:             result,
+		Operation:          "GetBucketACL",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -257,6 +265,7 @@ func (client *Client) SetBucketACLWithContext(ctx context.Context, bucketName st
 		Method:             HTTPPut,
 		QueryHeaderOptions: aclOption{""},
 		Data:               bytes.NewReader(aclBytes),
+		Operation:          "SetBucketACL",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -280,7 +289,7 @@ type LifecycleActionType string
 const (
 	Expiration                     LifecycleActionType = "expiration"
 	NonCurrentVersionExpiration    LifecycleActionType = "nonCurrentVersionExpiration"
-	AbortIncompleteMultipartUpload LifecycleActionType = "abortIncompleteMultipartUpload "
+	AbortIncompleteMultipartUpload LifecycleActionType = "abortIncompleteMultipartUpload"
 )
 
 // LifecycleAction is action in LifecycleRule
@@ -334,6 +343,7 @@ func (client *Client) GetLifecycleConfigWithContext(ctx context.Context, request
 		Method:             HTTPGet,
 		QueryHeaderOptions: request,
 		Result:             result,
+		Operation:          "GetLifecycleConfig",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -366,6 +376,7 @@ func (client *Client) SetLifecycleConfigWithContext(ctx context.Context, bucketN
 		Method:             HTTPPut,
 		QueryHeaderOptions: lifecycleOption{},
 		Data:               bytes.NewReader(data),
+		Operation:          "SetLifecycleConfig",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -394,6 +405,7 @@ func (client *Client) SetLifecycleRuleWithContext(ctx context.Context, bucketNam
 		Method:             HTTPPut,
 		QueryHeaderOptions: lifecycleOption{"rule"},
 		Data:               bytes.NewReader(data),
+		Operation:          "SetLifecycleRule",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -430,6 +442,7 @@ func (client *Client) GetAccessLogWithContext(ctx context.Context, bucketName st
 		Method:             HTTPGet,
 		QueryHeaderOptions: accessLogOption{},
 		Result:             result,
+		Operation:          "GetAccessLog",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -458,6 +471,7 @@ func (client *Client) SetAccessLogWithContext(ctx context.Context, bucketName st
 		Method:             HTTPPut,
 		QueryHeaderOptions: accessLogOption{},
 		Data:               bytes.NewReader(data),
+		Operation:          "SetAccessLog",
 	}
 
 	resp, err := client.do(ctx, req)
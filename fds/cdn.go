@@ -0,0 +1,134 @@
+package fds
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+type cdnPrefetchOption struct {
+	Prefetch string `param:"prefetch" header:"-"`
+}
+
+type cdnRefreshOption struct {
+	Refresh string `param:"refresh" header:"-"`
+}
+
+// PrefetchObject asks CDN to cache object before it's requested
+func (client *Client) PrefetchObject(bucketName, objectName string) error {
+	return client.PrefetchObjectWithContext(context.Background(), bucketName, objectName)
+}
+
+// PrefetchObjectWithContext asks CDN to cache object before it's requested with context controlling
+func (client *Client) PrefetchObjectWithContext(ctx context.Context, bucketName, objectName string) error {
+	return client.cdnCacheRequest(ctx, bucketName, objectName, cdnPrefetchOption{}, "PrefetchObject")
+}
+
+// RefreshObject asks CDN to drop cached object, e.g. after it's overwritten
+func (client *Client) RefreshObject(bucketName, objectName string) error {
+	return client.RefreshObjectWithContext(context.Background(), bucketName, objectName)
+}
+
+// RefreshObjectWithContext asks CDN to drop cached object with context controlling
+func (client *Client) RefreshObjectWithContext(ctx context.Context, bucketName, objectName string) error {
+	return client.cdnCacheRequest(ctx, bucketName, objectName, cdnRefreshOption{}, "RefreshObject")
+}
+
+func (client *Client) cdnCacheRequest(ctx context.Context, bucketName, objectName string, option interface{}, operation string) error {
+	req := &clientRequest{
+		BucketName:         bucketName,
+		ObjectName:         objectName,
+		Method:             HTTPPut,
+		QueryHeaderOptions: option,
+		Operation:          operation,
+	}
+
+	resp, err := client.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PrefetchURL prefetches object of u, which is a URL of object on CDN or origin
+func (client *Client) PrefetchURL(u *url.URL) error {
+	return client.PrefetchURLWithContext(context.Background(), u)
+}
+
+// PrefetchURLWithContext prefetches object of u with context controlling
+func (client *Client) PrefetchURLWithContext(ctx context.Context, u *url.URL) error {
+	bucketName, objectName, err := parseObjectURL(u)
+	if err != nil {
+		return err
+	}
+	return client.PrefetchObjectWithContext(ctx, bucketName, objectName)
+}
+
+// RefreshURL refreshes object of u, which is a URL of object on CDN or origin
+func (client *Client) RefreshURL(u *url.URL) error {
+	return client.RefreshURLWithContext(context.Background(), u)
+}
+
+// RefreshURLWithContext refreshes object of u with context controlling
+func (client *Client) RefreshURLWithContext(ctx context.Context, u *url.URL) error {
+	bucketName, objectName, err := parseObjectURL(u)
+	if err != nil {
+		return err
+	}
+	return client.RefreshObjectWithContext(ctx, bucketName, objectName)
+}
+
+// parseObjectURL returns bucket and object name of path /bucket/object
+func parseObjectURL(u *url.URL) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrorInvalidObjectURL
+	}
+	return parts[0], parts[1], nil
+}
+
+// PrefetchPrefix prefetches all objects with prefix in bucket, and returns how many objects are prefetched
+func (client *Client) PrefetchPrefix(bucketName, prefix string) (int, error) {
+	return client.PrefetchPrefixWithContext(context.Background(), bucketName, prefix)
+}
+
+// PrefetchPrefixWithContext prefetches all objects with prefix in bucket with context controlling
+func (client *Client) PrefetchPrefixWithContext(ctx context.Context, bucketName, prefix string) (int, error) {
+	return client.walkPrefix(ctx, bucketName, prefix, client.PrefetchObjectWithContext)
+}
+
+// RefreshPrefix refreshes all objects with prefix in bucket, and returns how many objects are refreshed
+func (client *Client) RefreshPrefix(bucketName, prefix string) (int, error) {
+	return client.RefreshPrefixWithContext(context.Background(), bucketName, prefix)
+}
+
+// RefreshPrefixWithContext refreshes all objects with prefix in bucket with context controlling
+func (client *Client) RefreshPrefixWithContext(ctx context.Context, bucketName, prefix string) (int, error) {
+	return client.walkPrefix(ctx, bucketName, prefix, client.RefreshObjectWithContext)
+}
+
+// walkPrefix calls fn on each object with prefix, it stops at the first error
+func (client *Client) walkPrefix(ctx context.Context, bucketName, prefix string,
+	fn func(ctx context.Context, bucketName, objectName string) error) (int, error) {
+	count := 0
+	listing, err := client.ListObjectsWithContext(ctx, &ListObjectsRequest{
+		BucketName: bucketName,
+		Prefix:     prefix,
+	})
+	for {
+		if err != nil {
+			return count, err
+		}
+		for _, object := range listing.ObjectSummaries {
+			if err := fn(ctx, bucketName, object.ObjectName); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if !listing.Truncated {
+			return count, nil
+		}
+		listing, err = client.ListObjectsNextBatchWithContext(ctx, listing)
+	}
+}
@@ -0,0 +1,116 @@
+package fds
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCDNTestClient(t *testing.T, cdn, origin http.HandlerFunc) *Client {
+	client := newTestClient(t, origin)
+	server := httptest.NewServer(cdn)
+	t.Cleanup(server.Close)
+	client.Configuration.cdnEndpoint = strings.TrimPrefix(server.URL, "http://")
+	client.Configuration.EnableCDNForDownload = true
+	return client
+}
+
+func TestCDN_GetObject(t *testing.T) {
+	var cdnHits, originHits int32
+	client := newCDNTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&cdnHits, 1)
+		switch r.URL.Path {
+		case "/bucket/cached":
+			w.Write([]byte("from cdn"))
+		case "/bucket/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&originHits, 1)
+		if r.URL.Path == "/bucket/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("from origin"))
+	})
+
+	body, err := client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "cached"})
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadAll(body)
+	assert.Equal(t, "from cdn", string(data))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&originHits))
+
+	body, err = client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "broken"})
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadAll(body)
+	assert.Equal(t, "from origin", string(data))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&originHits))
+
+	_, err = client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "missing"})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&originHits))
+
+	// metadata is never read from cdn
+	client.GetObjectMetadata("bucket", "cached")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&cdnHits))
+
+	client.Configuration.EnableCDNForDownload = false
+	body, err = client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "cached"})
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadAll(body)
+	assert.Equal(t, "from origin", string(data))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&cdnHits))
+}
+
+func TestCDN_GetObjectRanges(t *testing.T) {
+	client := newCDNTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bytes=0-1", r.Header.Get("Range"))
+		w.Header().Set("Content-Range", "bytes 0-1/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("ab"))
+	})
+
+	ranges, err := client.GetObjectRanges(&GetObjectRangesRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Ranges:     []httpparser.HTTPRange{{Start: 0, End: 1}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ranges))
+	data, _ := ioutil.ReadAll(ranges[0].Body)
+	assert.Equal(t, "ab", string(data))
+}
+
+func TestCDN_PrefetchRefresh(t *testing.T) {
+	var operations []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"name":"bucket","objects":[{"name":"a/1"},{"name":"a/2"}]}`))
+			return
+		}
+		assert.Equal(t, http.MethodPut, r.Method)
+		operations = append(operations, r.URL.RawQuery+" "+r.URL.Path)
+	})
+
+	assert.Nil(t, client.PrefetchObject("bucket", "x"))
+	u, _ := url.Parse("https://cdn.example.com/bucket/dir/y?GalaxyAccessKeyId=ak")
+	assert.Nil(t, client.RefreshURL(u))
+	n, err := client.RefreshPrefix("bucket", "a/")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"prefetch= /bucket/x", "refresh= /bucket/dir/y", "refresh= /bucket/a/1", "refresh= /bucket/a/2"}, operations)
+
+	u, _ = url.Parse("https://cdn.example.com/bucket")
+	assert.Equal(t, ErrorInvalidObjectURL, client.PrefetchURL(u))
+}
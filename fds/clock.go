@@ -0,0 +1,104 @@
+package fds
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+)
+
+// DefaultClockSkewThreshold is the default value of ClientConfiguration.ClockSkewThreshold
+const DefaultClockSkewThreshold = time.Minute
+
+// now is local time corrected by clock offset, it's used for Date header and pre-signed URLs
+func (client *Client) now() time.Time {
+	return time.Now().Add(client.ClockOffset())
+}
+
+// ClockSkew returns server time minus local time measured from Date header of the latest response
+func (client *Client) ClockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&client.clockSkew))
+}
+
+// ClockOffset returns offset added to local time when signing, it's set when
+// a request is rejected because of clock skew
+func (client *Client) ClockOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&client.clockOffset))
+}
+
+// dateHeader is the Date header of a response in HTTP date format
+type dateHeader struct {
+	Date time.Time `header:"Date"`
+}
+
+// responseClockSkew returns server time minus local time, ok is false if response has no valid Date
+func responseClockSkew(response *http.Response) (skew time.Duration, ok bool) {
+	if response == nil {
+		return 0, false
+	}
+	var date dateHeader
+	if err := httpparser.DecodeHeader(response.Header, &date); err != nil || date.Date.IsZero() {
+		return 0, false
+	}
+	return time.Until(date.Date), true
+}
+
+// observeClockSkew records skew measured from response
+func (client *Client) observeClockSkew(response *http.Response) {
+	if skew, ok := responseClockSkew(response); ok {
+		atomic.StoreInt64(&client.clockSkew, int64(skew))
+		client.Configuration.Metrics.observeClockSkew(skew)
+	}
+}
+
+// correctClockSkew updates clock offset if response is an authentication failure
+// caused by clock skew, and tells whether request should be signed and sent again
+func (client *Client) correctClockSkew(response *http.Response) bool {
+	if client.Configuration.DisableClockSkewCorrection || response == nil {
+		return false
+	}
+	if response.StatusCode != http.StatusForbidden && response.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	skew, ok := responseClockSkew(response)
+	if !ok {
+		return false
+	}
+
+	threshold := client.Configuration.ClockSkewThreshold
+	if threshold <= 0 {
+		threshold = DefaultClockSkewThreshold
+	}
+	diff := skew - client.ClockOffset()
+	if diff < threshold && diff > -threshold {
+		return false
+	}
+
+	atomic.StoreInt64(&client.clockOffset, int64(skew))
+	client.logger.Warn("fds clock skew corrected", Fields{LogFieldClockSkew: skew})
+	return true
+}
+
+// bodyRewinder returns a function seeking data back to where it's now, it's nil if
+// data could not be sent again
+func bodyRewinder(data io.Reader) func() error {
+	if data == nil {
+		return func() error { return nil }
+	}
+
+	seeker, ok := data.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	return func() error {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+}
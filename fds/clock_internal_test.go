@@ -0,0 +1,127 @@
+package fds
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSkewedServerClient returns a client of server whose clock is skew ahead of local
+func newSkewedServerClient(t *testing.T, skew time.Duration, requests *int32) (*Client, *signer.Verifier) {
+	serverNow := func() time.Time { return time.Now().Add(skew) }
+	verifier := signer.NewVerifier(func(accessKeyID string) (string, error) {
+		return "sk", nil
+	})
+	verifier.MaxSkew = 15 * time.Minute
+	verifier.Now = serverNow
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Header().Set("Date", serverNow().UTC().Format(http.TimeFormat))
+		ioutil.ReadAll(r.Body)
+		if _, err := verifier.Verify(r); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	return client, verifier
+}
+
+func TestClock_SkewCorrection(t *testing.T) {
+	var requests int32
+	client, verifier := newSkewedServerClient(t, time.Hour, &requests)
+
+	_, err := client.PutObject(&PutObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Data:       bytes.NewReader([]byte("hello")),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.InDelta(t, time.Hour.Seconds(), client.ClockSkew().Seconds(), 2)
+	assert.InDelta(t, time.Hour.Seconds(), client.ClockOffset().Seconds(), 2)
+
+	// offset is kept by following requests
+	_, err = client.GetObjectMetadata("bucket", "object")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// expiration of pre-signed URL is in server time
+	presigned, err := client.GeneratePresignedURL(&GeneratePresignedURLRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Method:     HTTPGet,
+		Expiration: time.Now().Add(time.Minute),
+		Metadata:   NewObjectMetadata(),
+	})
+	assert.Nil(t, err)
+	_, err = verifier.VerifyURL(string(HTTPGet), presigned, nil)
+	assert.Nil(t, err)
+}
+
+func TestClock_SkewCorrectionDisabled(t *testing.T) {
+	var requests int32
+	client, _ := newSkewedServerClient(t, time.Hour, &requests)
+	client.Configuration.DisableClockSkewCorrection = true
+
+	_, err := client.GetObjectMetadata("bucket", "object")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.InDelta(t, time.Hour.Seconds(), client.ClockSkew().Seconds(), 2)
+	assert.Equal(t, time.Duration(0), client.ClockOffset())
+}
+
+func TestClock_SkewNotRewindable(t *testing.T) {
+	var requests int32
+	client, _ := newSkewedServerClient(t, time.Hour, &requests)
+
+	// body which isn't an io.Seeker could not be sent again
+	_, err := client.PutObject(&PutObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Data:       io.MultiReader(bytes.NewReader([]byte("hello"))),
+		Progress:   &recordListener{},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestClock_SkewCorrectionWithProgress(t *testing.T) {
+	var requests int32
+	client, _ := newSkewedServerClient(t, time.Hour, &requests)
+
+	listener := &recordListener{}
+	_, err := client.PutObject(&PutObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Data:       bytes.NewReader([]byte("hello")),
+		Progress:   listener,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	listener.mu.Lock()
+	events := listener.events
+	listener.mu.Unlock()
+	last := events[len(events)-1]
+	assert.Equal(t, ProgressCompleted, last.Type)
+	assert.Equal(t, int64(5), last.ConsumedBytes)
+	assert.Equal(t, int64(5), last.TotalBytes)
+
+	retried := 0
+	for _, e := range events {
+		if e.Type == ProgressRetried {
+			retried++
+			assert.Equal(t, int64(-5), e.RwBytes)
+		}
+	}
+	assert.Equal(t, 1, retried)
+}
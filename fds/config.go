@@ -2,15 +2,24 @@ package fds
 
 import (
 	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
 )
 
 // HTTPTimeout defines HTTP timeout.
 type HTTPTimeout struct {
-	ConnectTimeout      time.Duration
-	ReadWriteTimeout    time.Duration
-	HeaderTimeout       time.Duration
+	ConnectTimeout time.Duration
+	// ReadWriteTimeout is the longest time request or response body could be idle,
+	// Timeout of ClientConfiguration in seconds is used if it's zero
+	ReadWriteTimeout time.Duration
+	// HeaderTimeout is the longest time waiting for response header after request is sent
+	HeaderTimeout time.Duration
+	// LongTimeout replaces HeaderTimeout for slow operations, e.g. CopyObject and CompleteMultipartUpload
 	LongTimeout         time.Duration
 	IdleConnTimeout     time.Duration
 	TLSHandshakeTimeout time.Duration
@@ -38,6 +47,47 @@ type ClientConfiguration struct {
 	DownloadBandwidth      uint64
 	UploadBandwidth        uint64
 	HTTPKeepAliveTimeoutMs uint64
+
+	// Logger is used by Client, a logrus logger of warn level is used if it's nil
+	Logger Logger
+
+	// Metrics collects prometheus metrics of Client if it's set, see NewMetrics
+	Metrics *Metrics
+
+	// Tracer starts a span for each FDS request if it's set
+	Tracer Tracer
+
+	// TLSConfig is used by HTTPS connections, e.g. for custom root CAs or client certificates
+	TLSConfig *tls.Config
+	// Proxy returns proxy of a request, ProxyURL is used if it's nil, and then http.ProxyFromEnvironment
+	Proxy func(*http.Request) (*url.URL, error)
+	// ProxyURL is the proxy of all requests
+	ProxyURL *url.URL
+	// MaxIdleConns limits idle connections of all hosts, 0 means no limit,
+	// while MaxConnection limits idle connections per host
+	MaxIdleConns int
+	// MaxConnsPerHost limits connections per host including those in use, 0 means no limit
+	MaxConnsPerHost int
+	// DisableHTTP2 stops upgrading HTTPS connections to HTTP/2
+	DisableHTTP2 bool
+	// DisableCSLB dials with net.Dialer instead of client-side load balancer
+	DisableCSLB bool
+
+	// Transport replaces the transport built from fields above if it's set,
+	// HTTPTimeout except ReadWriteTimeout is not applied to it
+	Transport http.RoundTripper
+	// HTTPClient is used as is if it's set, Transport is ignored then
+	HTTPClient *http.Client
+
+	// ClockSkewThreshold is the least skew between local and server time, which makes
+	// client correct its clock when a request is rejected, DefaultClockSkewThreshold is used if it's 0
+	ClockSkewThreshold time.Duration
+	// DisableClockSkewCorrection stops correcting clock and retrying requests rejected because of clock skew
+	DisableClockSkewCorrection bool
+
+	// Signer signs requests and pre-signed URLs, signer.Default is used if it's nil,
+	// set it if server has sub-resources other than signer.DefaultSubResources
+	Signer *signer.Signer
 }
 
 // NewClientConfiguration create a usable ClientConfiguration
@@ -72,6 +122,25 @@ func (conf *ClientConfiguration) CDNEndpoint() string {
 	return conf.cdnEndpoint
 }
 
+// proxy returns Proxy of http.Transport
+func (conf *ClientConfiguration) proxy() func(*http.Request) (*url.URL, error) {
+	if conf.Proxy != nil {
+		return conf.Proxy
+	}
+	if conf.ProxyURL != nil {
+		return http.ProxyURL(conf.ProxyURL)
+	}
+	return http.ProxyFromEnvironment
+}
+
+// readWriteTimeout falls back to Timeout if HTTPTimeout.ReadWriteTimeout is not set
+func (conf *ClientConfiguration) readWriteTimeout() time.Duration {
+	if conf.HTTPTimeout.ReadWriteTimeout > 0 {
+		return conf.HTTPTimeout.ReadWriteTimeout
+	}
+	return time.Duration(conf.Timeout) * time.Second
+}
+
 // RegionName get region name
 func (conf *ClientConfiguration) RegionName() string {
 	return conf.regionName
@@ -93,6 +162,8 @@ func defaultFDSClientConfiguration() *ClientConfiguration {
 	config.HTTPTimeout.TLSHandshakeTimeout = time.Second * 50
 	config.HTTPTimeout.KeepAliveTimeout = time.Second * 30
 	config.MaxConnection = 20
+	config.MaxIdleConns = DefaultMaxIdleConns
+	config.ClockSkewThreshold = DefaultClockSkewThreshold
 	config.BatchDeleteSize = 1000
 	config.RetryCount = 3
 	config.RetryInterval = 500 // ms
@@ -22,6 +22,7 @@ const (
 	HTTPHeaderAuthorization         = "authorization"
 	HTTPHeaderRange                 = "range"
 	HTTPHeaderContentRange          = "content-range"
+	HTTPHeaderETag                  = "etag"
 	HTTPHeaderContentMetadataLength = XiaomiMetaPrefix + HTTPHeaderContentLength
 	HTTPHeaderServerSideEncryption  = XiaomiMetaPrefix + "server-side-encryption"
 	HTTPHeaderStorageClass          = XiaomiMetaPrefix + "storage-class"
@@ -29,6 +30,7 @@ const (
 	HTTPHeaderRestoreExpireDate     = XiaomiMetaPrefix + "restore-expiry"
 	HTTPHeaderCRC64ECMA             = XiaomiMetaPrefix + "hash-crc64ecma"
 	HTTPHeaderMultipartUploadMode   = XiaomiPrefix + "multipart-upload-mode"
+	HTTPHeaderRequestID             = XiaomiPrefix + "request-id"
 )
 
 // HTTPMethod HTTP request method
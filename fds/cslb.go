@@ -20,9 +20,17 @@ type cslbDialer struct {
 	net.Dialer
 	maxNodeCount uint
 	lbs          sync.Map // string => *cslb.LoadBalancer
+	metrics      *Metrics
+	tracer       Tracer
 }
 
-func (d *cslbDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+func (d *cslbDialer) DialContext(ctx context.Context, network, address string) (conn net.Conn, err error) {
+	ctx, span := StartSpan(ctx, d.tracer, "fds.dial")
+	span.SetTag(TraceTagPeerHost, address)
+	defer func() {
+		FinishSpan(span, err)
+	}()
+
 	if host, port, err := net.SplitHostPort(address); err == nil {
 		val, ok := d.lbs.Load(host)
 		var lb *cslb.LoadBalancer
@@ -40,14 +48,23 @@ func (d *cslbDialer) DialContext(ctx context.Context, network, address string) (
 					MinHealthyNodeRatio: MinHealthyNodeRatio,
 					MaxNodeFailedRatio:  MaxNodeFailedRatio,
 				})
-			d.lbs.Store(host, lb)
+			if actual, loaded := d.lbs.LoadOrStore(host, lb); loaded {
+				lb = actual.(*cslb.LoadBalancer)
+			} else {
+				d.metrics.registerLoadBalancer(host, lb)
+			}
 		} else {
 			lb = val.(*cslb.LoadBalancer)
 		}
 		for i := 0; i < LBDialRetries; i++ {
 			if addr, err := lb.Next(); err == nil {
+				_, attemptSpan := StartSpan(ctx, d.tracer, "fds.dial.attempt")
+				attemptSpan.SetTag(TraceTagPeerIP, addr.String())
+				attemptSpan.SetTag(TraceTagAttempt, i+1)
 				conn, err := d.Dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+				FinishSpan(attemptSpan, err)
 				if err == nil {
+					span.SetTag(TraceTagPeerIP, addr.String())
 					return conn, nil
 				} else {
 					lb.NodeFailed(addr)
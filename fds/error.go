@@ -9,7 +9,28 @@ import (
 
 // Errors
 var (
-	ErrorEndpoint = errors.New("wrong endpoint")
+	ErrorEndpoint         = errors.New("wrong endpoint")
+	ErrorReadWriteTimeout = errors.New("read or write timeout")
+
+	ErrorInvalidUserMetadata = errors.New("user metadata conflicts with predefined metadata")
+	ErrorEmptyRanges         = errors.New("no range is requested")
+
+	ErrorLifecycleRuleID        = errors.New("lifecycle rule id is empty or duplicated")
+	ErrorLifecycleNoAction      = errors.New("lifecycle rule has no action")
+	ErrorLifecycleAction        = errors.New("unknown lifecycle action")
+	ErrorLifecycleDays          = errors.New("lifecycle days must be positive")
+	ErrorLifecyclePrefixOverlap = errors.New("lifecycle rules have overlapping prefixes")
+	ErrorLifecycleRuleNotFound  = errors.New("lifecycle rule is not found")
+	ErrorLifecycleConflict      = errors.New("lifecycle config is modified concurrently")
+
+	ErrorInvalidUploadPart = errors.New("uploadId and positive partNumber are required")
+	ErrorNotPresignedURL   = errors.New("not a presigned url")
+
+	ErrorInvalidObjectURL = errors.New("url has no bucket or object name")
+
+	ErrorNilACL = errors.New("desired acl is nil")
+
+	ErrorTrashBucketName = errors.New("bucket name of trash is empty")
 )
 
 // ServerError is a common structure for FDS client error
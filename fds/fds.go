@@ -6,28 +6,32 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
-
 	"github.com/XiaoMi/go-fds/fds/httpparser"
+	"github.com/XiaoMi/go-fds/fds/signer"
 )
 
 // Client supplies an interface for interaction with FDS
 type Client struct {
-	logger     *logrus.Logger
+	logger     Logger
 	httpClient *http.Client
 	transport  *http.Transport
+	// longHTTPClient waits for response header as long as HTTPTimeout.LongTimeout
+	longHTTPClient *http.Client
+
+	// clockSkew and clockOffset are durations in nanoseconds, accessed atomically
+	clockSkew   int64
+	clockOffset int64
 
 	Configuration *ClientConfiguration
 	AccessID      string
@@ -40,33 +44,48 @@ func New(accessID, accessSecret string, conf *ClientConfiguration) *Client {
 	client.Configuration = conf
 	client.AccessID = accessID
 	client.AccessSecret = accessSecret
-	// Ref: net/http.DefaultTransport
-	client.transport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&cslbDialer{
-			Dialer: net.Dialer{
-				Timeout:   conf.HTTPTimeout.ConnectTimeout,
-				KeepAlive: conf.HTTPTimeout.KeepAliveTimeout,
-			},
-			maxNodeCount: conf.MaxConnection,
-			lbs:          sync.Map{},
-		}).DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       conf.HTTPTimeout.IdleConnTimeout,
-		TLSHandshakeTimeout:   conf.HTTPTimeout.TLSHandshakeTimeout,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	client.httpClient = &http.Client{
-		Transport: client.transport,
-	}
-	client.logger = logrus.New()
-
-	client.logger.SetLevel(logrus.WarnLevel)
+	switch {
+	case conf.HTTPClient != nil:
+		client.httpClient = conf.HTTPClient
+		client.longHTTPClient = conf.HTTPClient
+	case conf.Transport != nil:
+		client.httpClient = &http.Client{
+			Transport: conf.Transport,
+		}
+		client.longHTTPClient = client.httpClient
+	default:
+		client.transport = newTransport(conf)
+		client.httpClient = &http.Client{
+			Transport: client.transport,
+		}
+
+		longTransport := client.transport.Clone()
+		longTransport.ResponseHeaderTimeout = conf.HTTPTimeout.LongTimeout
+		client.longHTTPClient = &http.Client{
+			Transport: longTransport,
+		}
+	}
+	client.logger = conf.Logger
+	if client.logger == nil {
+		client.logger = newDefaultLogger()
+	}
 
 	return client
 }
 
+// signer returns Signer of configuration or the default one
+func (client *Client) signer() *signer.Signer {
+	if client.Configuration.Signer != nil {
+		return client.Configuration.Signer
+	}
+	return signer.Default
+}
+
+// Logger returns logger of client
+func (client *Client) Logger() Logger {
+	return client.logger
+}
+
 type clientRequest struct {
 	BucketName         string
 	ObjectName         string
@@ -75,6 +94,14 @@ type clientRequest struct {
 	Metadata           *ObjectMetadata
 	Data               io.Reader
 	Result             interface{}
+	Progress           *ProgressTracker
+
+	// Operation is name of Client method, it's used in logs, metrics and traces
+	Operation string
+	// Long is set for slow operations, which wait for response header as long as HTTPTimeout.LongTimeout
+	Long bool
+	// CDN is set for downloads, which go through CDN endpoint if EnableCDNForDownload is set
+	CDN bool
 }
 
 // make request
@@ -100,11 +127,78 @@ func (client *Client) do(ctx context.Context, request *clientRequest) (*http.Res
 		}
 	}
 
-	return client.doRequest(ctx, request.Method, u, header, request.Data, request.Result)
+	// rewinder is taken from the underlying reader, progressReader isn't an io.Seeker
+	data := request.Data
+	rewind := bodyRewinder(data)
+	var progress *progressReader
+	if request.Progress != nil && data != nil {
+		progress = &progressReader{reader: data, tracker: request.Progress}
+		data = progress
+	}
+
+	if request.CDN && client.Configuration.EnableCDNForDownload && data == nil {
+		cdnURL := client.buildRequestURL(request.BucketName, request.ObjectName, query, true)
+		response, err := client.doRequest(ctx, request, cdnURL, header, nil)
+		if err == nil || !shouldFallbackToOrigin(ctx, err) {
+			return response, err
+		}
+		if response != nil {
+			response.Body.Close()
+		}
+		client.logger.Warn("fds cdn request failed, fallback to origin", Fields{
+			LogFieldOperation: request.Operation,
+			LogFieldBucket:    request.BucketName,
+			LogFieldObject:    request.ObjectName,
+			LogFieldError:     err,
+		})
+		client.Configuration.Metrics.ObserveRetry(request.Operation, request.BucketName)
+	}
+
+	response, err := client.doRequest(ctx, request, u, header, data)
+	if err != nil && rewind != nil && client.correctClockSkew(response) && rewind() == nil {
+		// sign again with corrected time, bytes sent by the rejected request aren't counted
+		progress.rollback(err)
+		client.Configuration.Metrics.ObserveRetry(request.Operation, request.BucketName)
+		return client.doRequest(ctx, request, u, header, data)
+	}
+	return response, err
+}
+
+// shouldFallbackToOrigin tells whether a failed CDN request should be sent to origin,
+// it's not if ctx is done or object doesn't exist or range is not satisfiable
+func shouldFallbackToOrigin(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		code := serverErr.Code()
+		return code != http.StatusNotFound && code != http.StatusRequestedRangeNotSatisfiable
+	}
+	return true
 }
 
-func (client *Client) doRequest(ctx context.Context, method HTTPMethod, url *url.URL, header http.Header,
-	data io.Reader, result interface{}) (*http.Response, error) {
+func (client *Client) doRequest(ctx context.Context, request *clientRequest, url *url.URL, header http.Header,
+	data io.Reader) (response *http.Response, err error) {
+	tracer := client.Configuration.Tracer
+	ctx, span := StartSpan(ctx, tracer, "fds."+request.Operation)
+	span.SetTag(TraceTagOperation, request.Operation)
+	span.SetTag(TraceTagBucket, request.BucketName)
+	span.SetTag(TraceTagObject, request.ObjectName)
+	span.SetTag(TraceTagMethod, string(request.Method))
+	defer func() {
+		FinishSpan(span, err)
+	}()
+
+	method := request.Method
+	result := request.Result
+	fields := Fields{
+		LogFieldOperation: request.Operation,
+		LogFieldBucket:    request.BucketName,
+		LogFieldObject:    request.ObjectName,
+		LogFieldMethod:    string(method),
+	}
+
 	methodString := strings.ToUpper(string(method))
 	req := &http.Request{
 		Method:     methodString,
@@ -116,10 +210,21 @@ func (client *Client) doRequest(ctx context.Context, method HTTPMethod, url *url
 		Host:       url.Host,
 	}
 
+	timeout := client.requestTimeout(ctx, request.Long)
+	ctx, wd := newWatchdog(ctx, timeout)
+	defer func() {
+		if err != nil {
+			wd.stop()
+		}
+	}()
+
 	// inject context
 	req = req.WithContext(ctx)
 
 	dataFile := client.doHandleRequestBody(req, data)
+	if req.Body != nil {
+		req.Body = &watchedReader{reader: req.Body, watchdog: wd}
+	}
 	if dataFile != nil {
 		defer func() {
 			dataFile.Close()
@@ -136,38 +241,69 @@ func (client *Client) doRequest(ctx context.Context, method HTTPMethod, url *url
 	data = dataFile
 
 	//req.Header.Add(HTTPHeaderContentMD5, "")
-	req.Header.Set(HTTPHeaderDate, time.Now().Format(time.RFC1123))
+	req.Header.Set(HTTPHeaderDate, client.now().UTC().Format(http.TimeFormat))
 
-	signature, err := signature(client.AccessSecret, method, url.String(), req.Header)
-	if err != nil {
-		return nil, err
+	if tracer != nil {
+		tracer.Inject(ctx, req.Header)
 	}
-	req.Header.Set(HTTPHeaderAuthorization, fmt.Sprintf("Galaxy-V2 %s:%s", client.AccessID, signature))
 
-	for k, v := range req.Header {
-		client.logger.Debug(fmt.Sprintf(" >>> HTTP Header: k=%s, v=%s", k, v))
-	}
-	client.logger.Debug(fmt.Sprintf(" >>> HTTP URL: %s", req.URL.String()))
+	client.signer().Sign(req, client.AccessID, client.AccessSecret)
+
+	client.logger.Debug("fds request", withFields(fields, Fields{
+		LogFieldURL:    redactURL(req.URL),
+		LogFieldHeader: redactHeader(req.Header),
+	}))
 
-	response, err := client.httpClient.Do(req)
+	start := time.Now()
+	observe := client.Configuration.Metrics.requestStarted(request.Operation, request.BucketName, req.ContentLength)
+	span.SetTag(TraceTagBytesSent, req.ContentLength)
+	httpClient := client.httpClient
+	if timeout.Long {
+		httpClient = client.longHTTPClient
+	}
+	response, err = httpClient.Do(req)
 	if err != nil {
+		observe(0)
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			err = wd.translate(ctx.Err())
 		default:
 		}
+		client.logger.Info("fds request failed", withFields(fields, Fields{
+			LogFieldLatency: time.Since(start),
+			LogFieldError:   err,
+		}))
 		return nil, err
 	}
 
+	observe(response.StatusCode)
+	client.observeClockSkew(response)
+	span.SetTag(TraceTagStatus, response.StatusCode)
+	span.SetTag(TraceTagBytesReceived, response.ContentLength)
+	wd.kick()
+	response.Body = &watchedReader{reader: response.Body, watchdog: wd, response: true}
+	response.Body = client.Configuration.Metrics.countReceived(request.Operation, request.BucketName, response.Body)
+
+	fields = withFields(fields, Fields{
+		LogFieldStatus:    response.StatusCode,
+		LogFieldLatency:   time.Since(start),
+		LogFieldRequestID: response.Header.Get(HTTPHeaderRequestID),
+	})
+
 	// check http status
 	statusNeed2Check := []int{http.StatusOK}
 	if method == HTTPHead {
 		statusNeed2Check = append(statusNeed2Check, http.StatusNotFound)
 	}
+	if method == HTTPGet {
+		statusNeed2Check = append(statusNeed2Check, http.StatusPartialContent)
+	}
 	err = checkResponseStatus(response, statusNeed2Check)
 	if err != nil {
+		client.logger.Info("fds request failed", withFields(fields, Fields{LogFieldError: err}))
 		return response, err
 	}
+	client.logger.Debug("fds response", fields)
 
 	// unmarshal response body into result
 	if result != nil {
@@ -217,19 +353,7 @@ func readResponseBody(resp *http.Response) ([]byte, error) {
 
 func (client *Client) doHandleRequestBody(req *http.Request, body io.Reader) *os.File {
 	var file *os.File
-	switch v := body.(type) {
-	case *bytes.Buffer:
-		req.ContentLength = int64(v.Len())
-	case *bytes.Reader:
-		req.ContentLength = int64(v.Len())
-	case *strings.Reader:
-		req.ContentLength = int64(v.Len())
-	case *os.File:
-		fileInfo, _ := v.Stat()
-		req.ContentLength = fileInfo.Size()
-	case *io.LimitedReader:
-		req.ContentLength = int64(v.N)
-	}
+	req.ContentLength, _ = readerLength(body)
 
 	req.Header.Set(HTTPHeaderContentLength, strconv.FormatInt(req.ContentLength, 10))
 
@@ -248,6 +372,29 @@ func (client *Client) doHandleRequestBody(req *http.Request, body io.Reader) *os
 	return file
 }
 
+// readerLength returns length of body if it's known
+func readerLength(body io.Reader) (int64, bool) {
+	switch v := body.(type) {
+	case *bytes.Buffer:
+		return int64(v.Len()), true
+	case *bytes.Reader:
+		return int64(v.Len()), true
+	case *strings.Reader:
+		return int64(v.Len()), true
+	case *os.File:
+		fileInfo, err := v.Stat()
+		if err != nil {
+			return 0, false
+		}
+		return fileInfo.Size(), true
+	case *io.LimitedReader:
+		return int64(v.N), true
+	case *progressReader:
+		return readerLength(v.reader)
+	}
+	return 0, false
+}
+
 func (client *Client) buildRequestURL(bucketName string, objectName string, params string, cdn bool) *url.URL {
 	var buf bytes.Buffer
 	basicURL := client.basicURL(cdn)
@@ -308,7 +455,7 @@ func (client *Client) jsonResponseUnmarshal(body io.Reader, v interface{}) error
 	}
 
 	e = json.Unmarshal(data, v)
-	client.logger.Debug(fmt.Sprintf(" <<< client Response: %s", v))
+	client.logger.Debug("fds response body", Fields{"size": len(data)})
 	return e
 }
 
@@ -33,75 +33,103 @@ func Header(v interface{}) (http.Header, error) {
 }
 
 func reflectHeader(header http.Header, val reflect.Value) error {
-	var embedded []reflect.Value
-
-	typ := val.Type()
+	fields, err := cachedFields(val.Type(), headerTag)
+	if err != nil {
+		return err
+	}
 
-	for i := 0; i < typ.NumField(); i++ {
-		tf := typ.Field(i)
-		if tf.PkgPath != "" && !tf.Anonymous { // unexported
+	for _, f := range fields {
+		vf := val.Field(f.index)
+		if f.embedded {
+			if err := reflectHeader(header, vf); err != nil {
+				return err
+			}
 			continue
 		}
 
-		vf := val.Field(i)
-		tag := tf.Tag.Get(headerTag)
-
-		if tag == skipTag {
+		if f.opts.Contains(omitemptyTag) && isEmpty(vf) {
 			continue
 		}
 
-		name, opts := parseTag(tag)
-		if name == emptyTag {
-			if tf.Anonymous && vf.Kind() == reflect.Struct {
-				embedded = append(embedded, vf)
-				continue
+		if vf.Type() == headerType {
+			h := vf.Interface().(http.Header)
+			for k, vs := range h {
+				for _, v := range vs {
+					header.Add(k, v)
+				}
 			}
-
-			name = tf.Name
+			continue
 		}
 
-		if opts.Contains(omitemptyTag) && vf.Kind() == reflect.Bool {
-			return fmt.Errorf("header: bool value can not be omitempty")
+		for vf.Kind() == reflect.Ptr && !isLeaf(vf.Type()) && !vf.IsNil() {
+			vf = vf.Elem()
 		}
 
-		if opts.Contains(omitemptyTag) && isEmpty(vf) {
+		if vf.Kind() == reflect.Struct && !isLeaf(vf.Type()) {
+			if err := reflectHeader(header, vf); err != nil {
+				return err
+			}
 			continue
 		}
 
-		if vf.Kind() == reflect.Ptr {
-			if vf.IsNil() {
-				break
-			}
-
-			vf = vf.Elem()
+		values, err := encodeValue(vf, f.opts)
+		if err != nil {
+			return fmt.Errorf("header: %s: %v", f.name, err)
+		}
+		for _, v := range values {
+			header.Add(f.name, v)
 		}
+	}
+	return nil
+}
+
+// DecodeHeader sets fields of struct pointed by v from header, it's the reverse of Header.
+// Fields without corresponding header are left unchanged, and a field of http.Header gets all of header.
+func DecodeHeader(header http.Header, v interface{}) error {
+	val, err := structValue(v, "header: DecodeHeader()")
+	if err != nil {
+		return err
+	}
+	return decodeHeader(header, val)
+}
+
+func decodeHeader(header http.Header, val reflect.Value) error {
+	fields, err := cachedFields(val.Type(), headerTag)
+	if err != nil {
+		return err
+	}
 
-		if vf.Type() == timeType {
-			header.Add(name, toString(vf, opts))
+	for _, f := range fields {
+		vf := val.Field(f.index)
+		if !vf.CanSet() && !f.embedded {
 			continue
 		}
 
-		if vf.Type() == headerType {
-			h := vf.Interface().(http.Header)
-			for k, vs := range h {
-				for _, v := range vs {
-					header.Add(k, v)
-				}
+		if f.embedded {
+			if err := decodeHeader(header, vf); err != nil {
+				return err
 			}
 			continue
 		}
 
-		if vf.Kind() == reflect.Struct {
-			reflectHeader(header, vf)
+		if vf.Type() == headerType {
+			vf.Set(reflect.ValueOf(header.Clone()))
 			continue
 		}
 
-		header.Add(name, toString(vf, opts))
-	}
+		if vf.Kind() == reflect.Struct && !isLeaf(vf.Type()) {
+			if err := decodeHeader(header, vf); err != nil {
+				return err
+			}
+			continue
+		}
 
-	for _, v := range embedded {
-		if err := reflectHeader(header, v); err != nil {
-			return err
+		values := header.Values(f.name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := decodeValue(vf, values, f.opts); err != nil {
+			return fmt.Errorf("header: %s: %v", f.name, err)
 		}
 	}
 	return nil
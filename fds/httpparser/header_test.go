@@ -2,6 +2,7 @@ package httpparser_test
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,7 +39,7 @@ func TestHeader(t *testing.T) {
 
 	assert.Equal(t, headers.Get("name"), "John")
 	assert.Equal(t, headers.Get("age"), "12")
-	assert.Equal(t, headers.Get("last-modified"), "01 Jan 18 01:01 UTC")
+	assert.Equal(t, headers.Get("last-modified"), "Mon, 01 Jan 2018 01:01:01 GMT")
 	assert.Empty(t, headers.Get("other"))
 	assert.Equal(t, headers.Get("can"), "false")
 	assert.Empty(t, headers.Get("content-length"))
@@ -55,3 +56,74 @@ func TestHeader(t *testing.T) {
 	assert.Equal(t, headers.Get("OtherOption"), "helloworld")
 
 }
+
+type commaList []string
+
+func (l commaList) MarshalHTTP() ([]string, error) {
+	return []string{strings.Join(l, ",")}, nil
+}
+
+func (l *commaList) UnmarshalHTTP(values []string) error {
+	*l = nil
+	for _, v := range values {
+		*l = append(*l, strings.Split(v, ",")...)
+	}
+	return nil
+}
+
+func TestDecodeHeader(t *testing.T) {
+	type User struct {
+		Name string `header:"name"`
+		Age  int    `header:"age"`
+	}
+	type testOptions struct {
+		User
+		LastModified time.Time   `header:"last-modified"`
+		UploadTime   time.Time   `header:"upload-time,unixmilli"`
+		Expires      *time.Time  `header:"expires,rfc1123"`
+		Can          bool        `header:"can"`
+		Size         *uint64     `header:"size"`
+		Tags         []string    `header:"tag"`
+		Methods      commaList   `header:"methods"`
+		Other        string      `header:"-"`
+		All          http.Header `header:",omitempty"`
+	}
+
+	header := http.Header{}
+	header.Set("name", "John")
+	header.Set("age", "12")
+	header.Set("last-modified", "Mon, 01 Jan 2018 01:01:01 GMT")
+	header.Set("upload-time", "1514768461000")
+	header.Set("expires", "Mon, 01 Jan 2018 09:01:01 CST")
+	header.Set("can", "true")
+	header.Set("size", "1024")
+	header.Add("tag", "a")
+	header.Add("tag", "b")
+	header.Set("methods", "GET,PUT")
+
+	option := testOptions{Other: "kept"}
+	assert.Nil(t, httpparser.DecodeHeader(header, &option))
+	assert.Equal(t, "John", option.Name)
+	assert.Equal(t, 12, option.Age)
+	assert.True(t, time.Date(2018, 1, 1, 1, 1, 1, 0, time.UTC).Equal(option.LastModified))
+	assert.True(t, option.LastModified.Equal(option.UploadTime))
+	assert.Equal(t, "CST", option.Expires.Format("MST"))
+	assert.True(t, option.Can)
+	assert.Equal(t, uint64(1024), *option.Size)
+	assert.Equal(t, []string{"a", "b"}, option.Tags)
+	assert.Equal(t, commaList{"GET", "PUT"}, option.Methods)
+	assert.Equal(t, "kept", option.Other)
+	assert.Equal(t, header, option.All)
+
+	// encoding is the reverse of decoding
+	option.All = nil
+	encoded, e := httpparser.Header(option)
+	assert.Nil(t, e)
+	for _, k := range []string{"name", "age", "last-modified", "upload-time", "can", "size", "tag", "methods"} {
+		assert.Equal(t, header.Values(k), encoded.Values(k), k)
+	}
+
+	header.Set("age", "twelve")
+	assert.NotNil(t, httpparser.DecodeHeader(header, &option))
+	assert.NotNil(t, httpparser.DecodeHeader(header, option))
+}
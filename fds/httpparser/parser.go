@@ -2,8 +2,11 @@ package httpparser
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +20,31 @@ const (
 	querystringTag = "param"
 )
 
+// Time format options of tag, HTTP date is used if there is none, e.g. `header:"expires,unixmilli"`
+const (
+	rfc1123Tag   = "rfc1123"
+	rfc822Tag    = "rfc822"
+	rfc3339Tag   = "rfc3339"
+	unixTag      = "unix"
+	unixMilliTag = "unixmilli"
+)
+
+// Marshaler is implemented by types which encode themselves into header or query values,
+// more than one value is added as repeated header or parameter
+type Marshaler interface {
+	MarshalHTTP() ([]string, error)
+}
+
+// Unmarshaler is implemented by types which decode themselves from header or query values
+type Unmarshaler interface {
+	UnmarshalHTTP(values []string) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
 type tags []string
 
 func parseTag(tag string) (string, tags) {
@@ -33,16 +61,248 @@ func (o tags) Contains(option string) bool {
 	return false
 }
 
+// field is a tagged struct field
+type field struct {
+	index int
+	name  string
+	opts  tags
+	// embedded is an anonymous struct without name, its fields are promoted
+	embedded bool
+}
+
+type cacheKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// fieldCache caches fields of struct types, cacheKey => []field
+var fieldCache sync.Map
+
+// cachedFields returns fields of struct type typ tagged by tag, embedded structs are at the end
+func cachedFields(typ reflect.Type, tag string) ([]field, error) {
+	key := cacheKey{typ, tag}
+	if v, ok := fieldCache.Load(key); ok {
+		return v.([]field), nil
+	}
+
+	var fields, embedded []field
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+
+		t := sf.Tag.Get(tag)
+		if t == skipTag {
+			continue
+		}
+
+		name, opts := parseTag(t)
+		if name == emptyTag {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				// save embedded struct for later processing
+				embedded = append(embedded, field{index: i, embedded: true})
+				continue
+			}
+
+			name = sf.Name
+		}
+
+		if opts.Contains(omitemptyTag) && sf.Type.Kind() == reflect.Bool {
+			return nil, fmt.Errorf("%s: bool value can not be omitempty", tag)
+		}
+
+		fields = append(fields, field{index: i, name: name, opts: opts})
+	}
+
+	fields = append(fields, embedded...)
+	fieldCache.Store(key, fields)
+	return fields, nil
+}
+
 func isEmpty(v reflect.Value) bool {
 	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
 }
 
-func toString(v reflect.Value, opts tags) string {
+// isLeaf tells whether v is encoded as values rather than a nested struct
+func isLeaf(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct || t == timeType
+}
 
-	if v.Type() == timeType {
-		t := v.Interface().(time.Time)
+// formatTime formats t by time format option of opts
+func formatTime(t time.Time, opts tags) string {
+	switch {
+	case opts.Contains(rfc1123Tag):
+		return t.Format(time.RFC1123)
+	case opts.Contains(rfc822Tag):
 		return t.Format(time.RFC822)
+	case opts.Contains(rfc3339Tag):
+		return t.Format(time.RFC3339)
+	case opts.Contains(unixTag):
+		return strconv.FormatInt(t.Unix(), 10)
+	case opts.Contains(unixMilliTag):
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// parseTime parses s by time format option of opts, HTTP date, RFC1123 and RFC3339 are
+// accepted if there is none
+func parseTime(s string, opts tags) (time.Time, error) {
+	switch {
+	case opts.Contains(rfc1123Tag):
+		return time.Parse(time.RFC1123, s)
+	case opts.Contains(rfc822Tag):
+		return time.Parse(time.RFC822, s)
+	case opts.Contains(rfc3339Tag):
+		return time.Parse(time.RFC3339, s)
+	case opts.Contains(unixTag), opts.Contains(unixMilliTag):
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if opts.Contains(unixTag) {
+			return time.Unix(n, 0), nil
+		}
+		return time.Unix(0, n*int64(time.Millisecond)), nil
+	}
+
+	if t, err := http.ParseTime(s); err == nil {
+		return t, nil
 	}
+	if t, err := time.Parse(time.RFC1123, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
 
-	return fmt.Sprint(v.Interface())
+// encodeValue turns v into values, slices are turned into repeated values
+func encodeValue(v reflect.Value, opts tags) ([]string, error) {
+	if v.Type().Implements(marshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface().(Marshaler).MarshalHTTP()
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(marshalerType) {
+		return v.Addr().Interface().(Marshaler).MarshalHTTP()
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem(), opts)
+	}
+
+	if v.Type() == timeType {
+		return []string{formatTime(v.Interface().(time.Time), opts)}, nil
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			s, err := encodeValue(v.Index(i), opts)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s...)
+		}
+		return values, nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		return []string{string(v.Bytes())}, nil
+	}
+
+	return []string{fmt.Sprint(v.Interface())}, nil
+}
+
+// decodeValue sets v from values, v must be settable
+func decodeValue(v reflect.Value, values []string, opts tags) error {
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		return v.Addr().Interface().(Unmarshaler).UnmarshalHTTP(values)
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(v.Elem(), values, opts)
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := decodeValue(slice.Index(i), []string{s}, opts); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+	s := values[0]
+
+	if v.Type() == timeType {
+		t, err := parseTime(s, opts)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// structValue dereferences v which should be a pointer to struct for decoding
+func structValue(v interface{}, fn string) (reflect.Value, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return reflect.Value{}, fmt.Errorf("%s expects non-nil pointer to struct. Got %v", fn, val.Kind())
+	}
+	val = val.Elem()
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%s expects non-nil pointer to struct. Got %v", fn, val.Kind())
+	}
+	return val, nil
 }
@@ -30,66 +30,97 @@ func QueryString(v interface{}) (url.Values, error) {
 }
 
 func reflectQueryString(values url.Values, val reflect.Value, scope string) error {
-	var embedded []reflect.Value
+	fields, err := cachedFields(val.Type(), querystringTag)
+	if err != nil {
+		return err
+	}
 
-	typ := val.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		sf := typ.Field(i)
-		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+	for _, f := range fields {
+		sv := val.Field(f.index)
+		if f.embedded {
+			if err := reflectQueryString(values, sv, scope); err != nil {
+				return err
+			}
 			continue
 		}
 
-		sv := val.Field(i)
-		tag := sf.Tag.Get(querystringTag)
-		if tag == skipTag {
+		name := scopedName(scope, f.name)
+		if f.opts.Contains(omitemptyTag) && isEmpty(sv) {
 			continue
 		}
-		name, opts := parseTag(tag)
-		if name == emptyTag {
-			if sf.Anonymous && sv.Kind() == reflect.Struct {
-				// save embedded struct for later processing
-				embedded = append(embedded, sv)
-				continue
-			}
 
-			name = sf.Name
+		for sv.Kind() == reflect.Ptr && !isLeaf(sv.Type()) && !sv.IsNil() {
+			sv = sv.Elem()
 		}
 
-		if scope != "" {
-			name = scope + "[" + name + "]"
+		if sv.Kind() == reflect.Struct && !isLeaf(sv.Type()) {
+			if err := reflectQueryString(values, sv, name); err != nil {
+				return err
+			}
+			continue
 		}
 
-		if opts.Contains(omitemptyTag) && sv.Kind() == reflect.Bool {
-			return fmt.Errorf("header: bool value can not be omitempty")
+		vs, err := encodeValue(sv, f.opts)
+		if err != nil {
+			return fmt.Errorf("querystring: %s: %v", name, err)
 		}
-
-		if opts.Contains(omitemptyTag) && isEmpty(sv) {
-			continue
+		for _, v := range vs {
+			values.Add(name, v)
 		}
+	}
+
+	return nil
+}
+
+func scopedName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "[" + name + "]"
+}
+
+// DecodeQuery sets fields of struct pointed by v from values, it's the reverse of QueryString.
+// Fields without corresponding parameter are left unchanged.
+func DecodeQuery(values url.Values, v interface{}) error {
+	val, err := structValue(v, "querystring: DecodeQuery()")
+	if err != nil {
+		return err
+	}
+	return decodeQuery(values, val, "")
+}
 
-		for sv.Kind() == reflect.Ptr {
-			if sv.IsNil() {
-				break
+func decodeQuery(values url.Values, val reflect.Value, scope string) error {
+	fields, err := cachedFields(val.Type(), querystringTag)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		sv := val.Field(f.index)
+		if f.embedded {
+			if err := decodeQuery(values, sv, scope); err != nil {
+				return err
 			}
-			sv = sv.Elem()
+			continue
 		}
-
-		if sv.Type() == timeType {
-			values.Add(name, toString(sv, opts))
+		if !sv.CanSet() {
 			continue
 		}
 
-		if sv.Kind() == reflect.Struct {
-			reflectQueryString(values, sv, name)
+		name := scopedName(scope, f.name)
+		if sv.Kind() == reflect.Struct && !isLeaf(sv.Type()) {
+			if err := decodeQuery(values, sv, name); err != nil {
+				return err
+			}
 			continue
 		}
 
-		values.Add(name, toString(sv, opts))
-	}
-
-	for _, f := range embedded {
-		if err := reflectQueryString(values, f, scope); err != nil {
-			return err
+		vs, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(sv, vs, f.opts); err != nil {
+			return fmt.Errorf("querystring: %s: %v", name, err)
 		}
 	}
 
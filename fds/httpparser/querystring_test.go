@@ -1,6 +1,7 @@
 package httpparser_test
 
 import (
+	"net/url"
 	"testing"
 	"time"
 
@@ -33,9 +34,41 @@ func TestQueryString(t *testing.T) {
 	}
 	values, e := httpparser.QueryString(option)
 	assert.Nil(t, e)
-	assert.Equal(t, values.Encode(), "OtherOption=helloworld&age=12&can=false&last-modified=01+Jan+18+01%3A01+UTC&name=John")
+	assert.Equal(t, values.Encode(), "OtherOption=helloworld&age=12&can=false&last-modified=Mon%2C+01+Jan+2018+01%3A01%3A01+GMT&name=John")
 	option.Can = true
 	values, e = httpparser.QueryString(option)
 	assert.Nil(t, e)
-	assert.Equal(t, values.Encode(), "OtherOption=helloworld&age=12&can=true&last-modified=01+Jan+18+01%3A01+UTC&name=John")
+	assert.Equal(t, values.Encode(), "OtherOption=helloworld&age=12&can=true&last-modified=Mon%2C+01+Jan+2018+01%3A01%3A01+GMT&name=John")
+}
+
+func TestDecodeQuery(t *testing.T) {
+	type page struct {
+		Marker  string `param:"marker"`
+		MaxKeys int    `param:"maxKeys"`
+	}
+	type testOptions struct {
+		Prefix  string    `param:"prefix"`
+		Page    page      `param:"page"`
+		Parts   []int     `param:"part"`
+		Deleted *bool     `param:"deleted"`
+		Missing string    `param:"missing"`
+		Since   time.Time `param:"since,unix"`
+	}
+
+	values, e := url.ParseQuery("prefix=logs%2F&page%5Bmarker%5D=m1&page%5BmaxKeys%5D=10&part=1&part=3&deleted=true&since=1514768461")
+	assert.Nil(t, e)
+
+	option := testOptions{Missing: "kept"}
+	assert.Nil(t, httpparser.DecodeQuery(values, &option))
+	assert.Equal(t, "logs/", option.Prefix)
+	assert.Equal(t, page{"m1", 10}, option.Page)
+	assert.Equal(t, []int{1, 3}, option.Parts)
+	assert.True(t, *option.Deleted)
+	assert.Equal(t, "kept", option.Missing)
+	assert.Equal(t, int64(1514768461), option.Since.Unix())
+
+	encoded, e := httpparser.QueryString(option)
+	assert.Nil(t, e)
+	encoded.Del("missing")
+	assert.Equal(t, values, encoded)
 }
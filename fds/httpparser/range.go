@@ -1,12 +1,23 @@
 package httpparser
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
-// HTTPRange is a struct replace Range string
+// Errors of range parsing
+var (
+	ErrRangeFormat         = errors.New("fds: error range format")
+	ErrRangeUnit           = errors.New("fds: only support bytes range")
+	ErrRangeNotSatisfiable = errors.New("fds: range not satisfiable")
+	ErrContentRangeFormat  = errors.New("fds: error content range format")
+)
+
+// HTTPRange is a byte range of RFC 7233, positions are inclusive. A negative Start
+// is a suffix range of the last -Start bytes, and a negative End means the range
+// ends at the last byte, e.g. bytes=-500 is {-500, -1} and bytes=100- is {100, -1}
 type HTTPRange struct {
 	Start int64
 	End   int64
@@ -23,42 +34,186 @@ func Range(r string) ([]HTTPRange, error) {
 	index := strings.Index(r, "=")
 
 	if index == -1 {
-		return ranges, fmt.Errorf("fds: error range format")
+		return ranges, ErrRangeFormat
 	}
 
-	if r[0:index] != "bytes" {
-		return ranges, fmt.Errorf("fds: only support bytes range")
+	if strings.TrimSpace(r[0:index]) != "bytes" {
+		return ranges, ErrRangeUnit
 	}
 
-	rangeBody := r[index+1:]
-	splitRange := strings.Split(rangeBody, ",")
+	for _, item := range strings.Split(r[index+1:], ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			// empty list elements are allowed by RFC 7230
+			continue
+		}
 
-	for _, item := range splitRange {
-		splitItem := strings.Split(item, "-")
-		if len(splitItem) != 2 {
-			return ranges, fmt.Errorf("fds: error range format")
+		spec, err := parseRangeSpec(item)
+		if err != nil {
+			return nil, err
 		}
+		ranges = append(ranges, spec)
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrRangeFormat
+	}
+	return ranges, nil
+}
+
+func parseRangeSpec(item string) (HTTPRange, error) {
+	i := strings.Index(item, "-")
+	if i == -1 {
+		return HTTPRange{}, ErrRangeFormat
+	}
+	first, last := item[:i], item[i+1:]
 
-		var start int64
-		var err error
-		if splitItem[0] != "" {
-			start, err = strconv.ParseInt(splitItem[0], 10, 0)
-			if err != nil {
-				return ranges, fmt.Errorf("fds: error range format")
-			}
+	if first == "" {
+		// suffix-byte-range-spec
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return HTTPRange{}, ErrRangeFormat
 		}
+		return HTTPRange{Start: -n, End: -1}, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return HTTPRange{}, ErrRangeFormat
+	}
+	if last == "" {
+		return HTTPRange{Start: start, End: -1}, nil
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return HTTPRange{}, ErrRangeFormat
+	}
+	return HTTPRange{Start: start, End: end}, nil
+}
+
+// String formats r as a byte-range-spec without unit, e.g. 0-99, 100- or -500
+func (r HTTPRange) String() string {
+	if r.Start < 0 {
+		return strconv.FormatInt(r.Start, 10)
+	}
+	if r.End < 0 {
+		return strconv.FormatInt(r.Start, 10) + "-"
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// FormatRange formats ranges as value of Range header
+func FormatRange(ranges ...HTTPRange) string {
+	specs := make([]string, len(ranges))
+	for i, r := range ranges {
+		specs[i] = r.String()
+	}
+	return "bytes=" + strings.Join(specs, ",")
+}
 
-		var end int64
-		if splitItem[1] == "" {
-			return ranges, fmt.Errorf("fds: error range format")
+// Resolve turns r into absolute positions of an object of size, ErrRangeNotSatisfiable
+// is returned if r is out of the object
+func (r HTTPRange) Resolve(size int64) (HTTPRange, error) {
+	start, end := r.Start, r.End
+	if start < 0 {
+		start += size
+		if start < 0 {
+			start = 0
 		}
-		end, err = strconv.ParseInt(splitItem[1], 10, 0)
-		if err != nil {
-			return ranges, fmt.Errorf("fds: error range format")
+		end = size - 1
+	}
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+
+	if start >= size || start > end {
+		return HTTPRange{}, ErrRangeNotSatisfiable
+	}
+	return HTTPRange{Start: start, End: end}, nil
+}
+
+// Length is count of bytes of a resolved range
+func (r HTTPRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// ResolveRanges resolves ranges against size and drops unsatisfiable ones,
+// ErrRangeNotSatisfiable is returned if none is satisfiable
+func ResolveRanges(ranges []HTTPRange, size int64) ([]HTTPRange, error) {
+	var resolved []HTTPRange
+	for _, r := range ranges {
+		if rr, err := r.Resolve(size); err == nil {
+			resolved = append(resolved, rr)
 		}
+	}
+	if len(resolved) == 0 {
+		return nil, ErrRangeNotSatisfiable
+	}
+	return resolved, nil
+}
+
+// ContentRange is value of Content-Range header. Size is -1 if it's unknown,
+// and Unsatisfied is true for bytes */size in 416 responses
+type ContentRange struct {
+	Start       int64
+	End         int64
+	Size        int64
+	Unsatisfied bool
+}
 
-		ranges = append(ranges, HTTPRange{start, end})
+// ParseContentRange parses value of Content-Range header, e.g. bytes 0-99/1000
+func ParseContentRange(s string) (ContentRange, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "bytes ") {
+		return ContentRange{}, ErrContentRangeFormat
 	}
+	s = strings.TrimSpace(strings.TrimPrefix(s, "bytes "))
 
-	return ranges, nil
+	i := strings.Index(s, "/")
+	if i == -1 {
+		return ContentRange{}, ErrContentRangeFormat
+	}
+	resp, sizeStr := s[:i], s[i+1:]
+
+	cr := ContentRange{Size: -1}
+	if sizeStr != "*" {
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil || size < 0 {
+			return ContentRange{}, ErrContentRangeFormat
+		}
+		cr.Size = size
+	}
+
+	if resp == "*" {
+		if cr.Size < 0 {
+			return ContentRange{}, ErrContentRangeFormat
+		}
+		cr.Unsatisfied = true
+		return cr, nil
+	}
+
+	r, err := parseRangeSpec(resp)
+	if err != nil || r.Start < 0 || r.End < 0 || (cr.Size >= 0 && r.End >= cr.Size) {
+		return ContentRange{}, ErrContentRangeFormat
+	}
+	cr.Start, cr.End = r.Start, r.End
+	return cr, nil
+}
+
+// Range returns the resolved range of cr
+func (cr ContentRange) Range() HTTPRange {
+	return HTTPRange{Start: cr.Start, End: cr.End}
+}
+
+// String formats cr as value of Content-Range header
+func (cr ContentRange) String() string {
+	size := "*"
+	if cr.Size >= 0 {
+		size = strconv.FormatInt(cr.Size, 10)
+	}
+	if cr.Unsatisfied {
+		return "bytes */" + size
+	}
+	return fmt.Sprintf("bytes %d-%d/%s", cr.Start, cr.End, size)
 }
@@ -32,12 +32,77 @@ func TestRange(t *testing.T) {
 
 	assert.Equal(t, len(ranges), 3)
 
-	assert.Equal(t, ranges[0].Start, int64(0))
-	assert.Equal(t, ranges[0].End, int64(1))
+	// suffix ranges
+	assert.Equal(t, ranges[0].Start, int64(-1))
+	assert.Equal(t, ranges[0].End, int64(-1))
 
 	assert.Equal(t, ranges[1].Start, int64(2))
 	assert.Equal(t, ranges[1].End, int64(4))
 
-	assert.Equal(t, ranges[2].Start, int64(0))
-	assert.Equal(t, ranges[2].End, int64(5))
+	assert.Equal(t, ranges[2].Start, int64(-5))
+	assert.Equal(t, ranges[2].End, int64(-1))
+
+	ranges, err = httpparser.Range("bytes=100-, 0-0")
+	assert.Nil(t, err)
+	assert.Equal(t, []httpparser.HTTPRange{{Start: 100, End: -1}, {Start: 0, End: 0}}, ranges)
+	assert.Equal(t, "bytes=100-,0-0", httpparser.FormatRange(ranges...))
+	assert.Equal(t, "bytes=-1,2-4,-5", httpparser.FormatRange([]httpparser.HTTPRange{{Start: -1, End: -1}, {Start: 2, End: 4}, {Start: -5, End: -1}}...))
+
+	for _, invalid := range []string{"bytes", "bytes=", "items=0-1", "bytes=1", "bytes=-", "bytes=5-1", "bytes=a-b", "bytes=-0"} {
+		_, err = httpparser.Range(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestRangeResolve(t *testing.T) {
+	cases := []struct {
+		r        httpparser.HTTPRange
+		size     int64
+		expected httpparser.HTTPRange
+		err      error
+	}{
+		{httpparser.HTTPRange{Start: 0, End: 99}, 1000, httpparser.HTTPRange{Start: 0, End: 99}, nil},
+		{httpparser.HTTPRange{Start: 900, End: 1999}, 1000, httpparser.HTTPRange{Start: 900, End: 999}, nil},
+		{httpparser.HTTPRange{Start: 100, End: -1}, 1000, httpparser.HTTPRange{Start: 100, End: 999}, nil},
+		{httpparser.HTTPRange{Start: -500, End: -1}, 1000, httpparser.HTTPRange{Start: 500, End: 999}, nil},
+		{httpparser.HTTPRange{Start: -5000, End: -1}, 1000, httpparser.HTTPRange{Start: 0, End: 999}, nil},
+		{httpparser.HTTPRange{Start: 1000, End: -1}, 1000, httpparser.HTTPRange{}, httpparser.ErrRangeNotSatisfiable},
+		{httpparser.HTTPRange{Start: -1, End: -1}, 0, httpparser.HTTPRange{}, httpparser.ErrRangeNotSatisfiable},
+	}
+	for _, c := range cases {
+		resolved, err := c.r.Resolve(c.size)
+		assert.Equal(t, c.err, err, c.r.String())
+		assert.Equal(t, c.expected, resolved, c.r.String())
+	}
+
+	resolved, err := httpparser.ResolveRanges([]httpparser.HTTPRange{{Start: 2000, End: -1}, {Start: -10, End: -1}}, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, []httpparser.HTTPRange{{Start: 990, End: 999}}, resolved)
+	assert.Equal(t, int64(10), resolved[0].Length())
+
+	_, err = httpparser.ResolveRanges([]httpparser.HTTPRange{{Start: 2000, End: -1}}, 1000)
+	assert.Equal(t, httpparser.ErrRangeNotSatisfiable, err)
+}
+
+func TestContentRange(t *testing.T) {
+	cr, err := httpparser.ParseContentRange("bytes 0-99/1000")
+	assert.Nil(t, err)
+	assert.Equal(t, httpparser.ContentRange{Start: 0, End: 99, Size: 1000}, cr)
+	assert.Equal(t, httpparser.HTTPRange{Start: 0, End: 99}, cr.Range())
+	assert.Equal(t, "bytes 0-99/1000", cr.String())
+
+	cr, err = httpparser.ParseContentRange("bytes 10-19/*")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), cr.Size)
+	assert.Equal(t, "bytes 10-19/*", cr.String())
+
+	cr, err = httpparser.ParseContentRange("bytes */1000")
+	assert.Nil(t, err)
+	assert.True(t, cr.Unsatisfied)
+	assert.Equal(t, "bytes */1000", cr.String())
+
+	for _, invalid := range []string{"", "bytes 0-99", "items 0-9/10", "bytes 5-1/10", "bytes 0-10/10", "bytes */*", "bytes -5/10"} {
+		_, err = httpparser.ParseContentRange(invalid)
+		assert.NotNil(t, err, invalid)
+	}
 }
@@ -0,0 +1,566 @@
+package fds
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lifecycleDay is length of a day in lifecycle rules
+const lifecycleDay = 24 * time.Hour
+
+// LifecycleRuleBuilder builds a LifecycleRule fluently, e.g.
+//
+//	rule, err := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+type LifecycleRuleBuilder struct {
+	rule LifecycleRule
+}
+
+// NewLifecycleRuleBuilder returns a builder of an enabled rule with id
+func NewLifecycleRuleBuilder(id string) *LifecycleRuleBuilder {
+	return &LifecycleRuleBuilder{
+		rule: LifecycleRule{
+			ID:      id,
+			Enabled: true,
+			Action:  LifecycleAction{},
+		},
+	}
+}
+
+// Prefix limits the rule to objects with prefix, the rule applies to the whole bucket by default
+func (b *LifecycleRuleBuilder) Prefix(prefix string) *LifecycleRuleBuilder {
+	b.rule.Prefix = prefix
+	return b
+}
+
+// Enabled sets whether the rule is enabled
+func (b *LifecycleRuleBuilder) Enabled(enabled bool) *LifecycleRuleBuilder {
+	b.rule.Enabled = enabled
+	return b
+}
+
+// Expire deletes objects days after they are last modified
+func (b *LifecycleRuleBuilder) Expire(days float64) *LifecycleRuleBuilder {
+	return b.action(Expiration, days)
+}
+
+// ExpireNonCurrentVersion deletes non-current versions days after they become non-current
+func (b *LifecycleRuleBuilder) ExpireNonCurrentVersion(days float64) *LifecycleRuleBuilder {
+	return b.action(NonCurrentVersionExpiration, days)
+}
+
+// AbortIncompleteMultipartUpload aborts multipart uploads days after they are initiated
+func (b *LifecycleRuleBuilder) AbortIncompleteMultipartUpload(days float64) *LifecycleRuleBuilder {
+	return b.action(AbortIncompleteMultipartUpload, days)
+}
+
+func (b *LifecycleRuleBuilder) action(action LifecycleActionType, days float64) *LifecycleRuleBuilder {
+	b.rule.Action[action] = LifecycleBaseItem{Days: days}
+	return b
+}
+
+// Build validates and returns the rule, the builder can be reused after Build
+func (b *LifecycleRuleBuilder) Build() (*LifecycleRule, error) {
+	rule := b.rule
+	rule.Action = make(LifecycleAction, len(b.rule.Action))
+	for k, v := range b.rule.Action {
+		rule.Action[k] = v
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// legacyAbortIncompleteMultipartUpload is the key with a trailing space written by older
+// versions of this package, it's still found in existing configs
+const legacyAbortIncompleteMultipartUpload LifecycleActionType = "abortIncompleteMultipartUpload "
+
+func isLifecycleAction(action LifecycleActionType) bool {
+	switch action {
+	case Expiration, NonCurrentVersionExpiration, AbortIncompleteMultipartUpload, legacyAbortIncompleteMultipartUpload:
+		return true
+	}
+	return false
+}
+
+// Validate checks the rule has an id and known actions with positive days
+func (rule *LifecycleRule) Validate() error {
+	if rule.ID == "" {
+		return ErrorLifecycleRuleID
+	}
+	if len(rule.Action) == 0 {
+		return fmt.Errorf("%w: rule %q", ErrorLifecycleNoAction, rule.ID)
+	}
+	for action, item := range rule.Action {
+		if !isLifecycleAction(action) {
+			return fmt.Errorf("%w: rule %q, action %q", ErrorLifecycleAction, rule.ID, action)
+		}
+		if item.Days <= 0 {
+			return fmt.Errorf("%w: rule %q, action %q", ErrorLifecycleDays, rule.ID, action)
+		}
+	}
+	return nil
+}
+
+// Validate checks every rule, duplicate ids and enabled rules with overlapping prefixes,
+// a rule with an empty prefix applies to the whole bucket and overlaps every other rule
+func (config *LifecycleConfig) Validate() error {
+	ids := make(map[string]bool, len(config.Rules))
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("%w: rule %q", ErrorLifecycleRuleID, rule.ID)
+		}
+		ids[rule.ID] = true
+	}
+
+	for i := range config.Rules {
+		for j := i + 1; j < len(config.Rules); j++ {
+			a, b := &config.Rules[i], &config.Rules[j]
+			if a.Enabled && b.Enabled && prefixesOverlap(a.Prefix, b.Prefix) {
+				return fmt.Errorf("%w: rule %q and %q", ErrorLifecyclePrefixOverlap, a.ID, b.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func prefixesOverlap(a, b string) bool {
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// validateRule checks rule with id in config and whether it conflicts with the others,
+// other rules aren't checked so a config already on the bucket never blocks a change
+func (config *LifecycleConfig) validateRule(id string) error {
+	i := config.index(id)
+	if i < 0 {
+		return fmt.Errorf("%w: rule %q", ErrorLifecycleRuleNotFound, id)
+	}
+	rule := &config.Rules[i]
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	for j := range config.Rules {
+		other := &config.Rules[j]
+		if j == i {
+			continue
+		}
+		if other.ID == rule.ID {
+			return fmt.Errorf("%w: rule %q", ErrorLifecycleRuleID, rule.ID)
+		}
+		if rule.Enabled && other.Enabled && prefixesOverlap(rule.Prefix, other.Prefix) {
+			return fmt.Errorf("%w: rule %q and %q", ErrorLifecyclePrefixOverlap, rule.ID, other.ID)
+		}
+	}
+	return nil
+}
+
+// LifecycleEvaluation is the action predicted for an object
+type LifecycleEvaluation struct {
+	Rule   *LifecycleRule
+	Action LifecycleActionType
+	// DueAt is when the action applies, Due is true if it's not after the evaluation time
+	DueAt time.Time
+	Due   bool
+}
+
+// objectModifiedTime is the time lifecycle ages of object start from
+func objectModifiedTime(object *ObjectSummary) time.Time {
+	if !object.LastModified.IsZero() {
+		return object.LastModified
+	}
+	if object.UploadTime > 0 {
+		return time.Unix(0, object.UploadTime*int64(time.Millisecond))
+	}
+	return time.Time{}
+}
+
+// Evaluate predicts which action of config applies to object at now. Only expiration of
+// current objects can be predicted from an ObjectSummary, the earliest one is returned
+// if more than one rule matches, and nil is returned if none does.
+func Evaluate(config *LifecycleConfig, object *ObjectSummary, now time.Time) *LifecycleEvaluation {
+	if config == nil || object == nil {
+		return nil
+	}
+
+	modified := objectModifiedTime(object)
+	if modified.IsZero() {
+		return nil
+	}
+
+	var result *LifecycleEvaluation
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if !rule.Enabled || !strings.HasPrefix(object.ObjectName, rule.Prefix) {
+			continue
+		}
+		item, ok := rule.Action[Expiration]
+		if !ok || item.Days <= 0 {
+			continue
+		}
+
+		dueAt := modified.Add(time.Duration(item.Days * float64(lifecycleDay)))
+		if result == nil || dueAt.Before(result.DueAt) {
+			result = &LifecycleEvaluation{
+				Rule:   rule,
+				Action: Expiration,
+				DueAt:  dueAt,
+				Due:    !dueAt.After(now),
+			}
+		}
+	}
+	return result
+}
+
+// LifecycleReportItem is an object matched by a rule in a dry run
+type LifecycleReportItem struct {
+	ObjectName string
+	Size       int64
+	RuleID     string
+	Action     LifecycleActionType
+	DueAt      time.Time
+	Due        bool
+}
+
+// LifecycleRuleStats sums objects matched by a rule
+type LifecycleRuleStats struct {
+	Objects    int64
+	Bytes      int64
+	DueObjects int64
+	DueBytes   int64
+}
+
+// LifecycleReport is result of DryRunLifecycle
+type LifecycleReport struct {
+	BucketName  string
+	EvaluatedAt time.Time
+	Scanned     int64
+	// Items are sorted by DueAt, objects matched by no rule are not included
+	Items []LifecycleReportItem
+	Rules map[string]*LifecycleRuleStats
+}
+
+// DryRunLifecycleRequest is input of DryRunLifecycle
+type DryRunLifecycleRequest struct {
+	BucketName string
+	Config     *LifecycleConfig
+	// Prefix limits objects listed, Now is time of evaluation, time.Now() if it's zero
+	Prefix string
+	Now    time.Time
+}
+
+// DryRunLifecycle evaluates Config over all objects of bucket without changing anything,
+// it's useful to review a policy before SetLifecycleConfig
+func (client *Client) DryRunLifecycle(request *DryRunLifecycleRequest) (*LifecycleReport, error) {
+	return client.DryRunLifecycleWithContext(context.Background(), request)
+}
+
+// DryRunLifecycleWithContext evaluates Config over all objects of bucket with context controlling
+func (client *Client) DryRunLifecycleWithContext(ctx context.Context, request *DryRunLifecycleRequest) (*LifecycleReport, error) {
+	if err := request.Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := request.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	report := &LifecycleReport{
+		BucketName:  request.BucketName,
+		EvaluatedAt: now,
+		Rules:       make(map[string]*LifecycleRuleStats),
+	}
+	for _, rule := range request.Config.Rules {
+		report.Rules[rule.ID] = &LifecycleRuleStats{}
+	}
+
+	listing, err := client.ListObjectsWithContext(ctx, &ListObjectsRequest{
+		BucketName: request.BucketName,
+		Prefix:     request.Prefix,
+	})
+	for {
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range listing.ObjectSummaries {
+			object := &listing.ObjectSummaries[i]
+			report.Scanned++
+
+			e := Evaluate(request.Config, object, now)
+			if e == nil {
+				continue
+			}
+			report.Items = append(report.Items, LifecycleReportItem{
+				ObjectName: object.ObjectName,
+				Size:       object.Size,
+				RuleID:     e.Rule.ID,
+				Action:     e.Action,
+				DueAt:      e.DueAt,
+				Due:        e.Due,
+			})
+
+			stats := report.Rules[e.Rule.ID]
+			stats.Objects++
+			stats.Bytes += object.Size
+			if e.Due {
+				stats.DueObjects++
+				stats.DueBytes += object.Size
+			}
+		}
+
+		if !listing.Truncated {
+			break
+		}
+		listing, err = client.ListObjectsNextBatchWithContext(ctx, listing)
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		return report.Items[i].DueAt.Before(report.Items[j].DueAt)
+	})
+	return report, nil
+}
+
+// lifecycleUpdateAttempts is how many times a read-modify-write of lifecycle config is tried
+// before ErrorLifecycleConflict is returned
+const lifecycleUpdateAttempts = 3
+
+// updateLifecycleConfig reads lifecycle config of bucket, changes it by update and writes it back
+// if it's changed, update validates rules it touches. The config is read again before writing,
+// and the whole update is retried if someone else has modified it in between. Detection is best
+// effort since the service has no conditional write, a change landing between the second read
+// and the write is still overwritten.
+func (client *Client) updateLifecycleConfig(ctx context.Context, bucketName string, update func(*LifecycleConfig) error) error {
+	for i := 0; i < lifecycleUpdateAttempts; i++ {
+		current, err := client.GetLifecycleConfigWithContext(ctx, &GetLifecycleConfigRequest{BucketName: bucketName})
+		if err != nil {
+			return err
+		}
+
+		config := current.clone()
+		if err := update(config); err != nil {
+			return err
+		}
+		if lifecycleConfigEqual(current, config) {
+			return nil
+		}
+
+		latest, err := client.GetLifecycleConfigWithContext(ctx, &GetLifecycleConfigRequest{BucketName: bucketName})
+		if err != nil {
+			return err
+		}
+		if !lifecycleConfigEqual(current, latest) {
+			continue
+		}
+		return client.SetLifecycleConfigWithContext(ctx, bucketName, config)
+	}
+	return ErrorLifecycleConflict
+}
+
+func (config *LifecycleConfig) clone() *LifecycleConfig {
+	result := &LifecycleConfig{Rules: make([]LifecycleRule, len(config.Rules))}
+	for i, rule := range config.Rules {
+		result.Rules[i] = *rule.clone()
+	}
+	return result
+}
+
+func (rule *LifecycleRule) clone() *LifecycleRule {
+	result := *rule
+	result.Action = make(LifecycleAction, len(rule.Action))
+	for k, v := range rule.Action {
+		result.Action[k] = v
+	}
+	return &result
+}
+
+// index returns position of rule with id, -1 if there is none
+func (config *LifecycleConfig) index(id string) int {
+	for i := range config.Rules {
+		if config.Rules[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func lifecycleRuleEqual(a, b *LifecycleRule) bool {
+	if a.ID != b.ID || a.Prefix != b.Prefix || a.Enabled != b.Enabled || len(a.Action) != len(b.Action) {
+		return false
+	}
+	for k, v := range a.Action {
+		if w, ok := b.Action[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func lifecycleConfigEqual(a, b *LifecycleConfig) bool {
+	if len(a.Rules) != len(b.Rules) {
+		return false
+	}
+	for i := range a.Rules {
+		if !lifecycleRuleEqual(&a.Rules[i], &b.Rules[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteLifecycleRule deletes rule with id from lifecycle config of bucket, other rules are kept
+func (client *Client) DeleteLifecycleRule(bucketName, id string) error {
+	return client.DeleteLifecycleRuleWithContext(context.Background(), bucketName, id)
+}
+
+// DeleteLifecycleRuleWithContext deletes rule with id from lifecycle config of bucket with context controlling
+func (client *Client) DeleteLifecycleRuleWithContext(ctx context.Context, bucketName, id string) error {
+	return client.updateLifecycleConfig(ctx, bucketName, func(config *LifecycleConfig) error {
+		i := config.index(id)
+		if i < 0 {
+			return fmt.Errorf("%w: rule %q", ErrorLifecycleRuleNotFound, id)
+		}
+		config.Rules = append(config.Rules[:i], config.Rules[i+1:]...)
+		return nil
+	})
+}
+
+// UpsertLifecycleRule adds rule to lifecycle config of bucket or replaces the rule with the same id.
+// ErrorLifecyclePrefixOverlap is returned if it conflicts with rules of others, and
+// ErrorLifecycleConflict is returned if the config is seen being modified concurrently
+// on every attempt, see updateLifecycleConfig for limits of the detection.
+func (client *Client) UpsertLifecycleRule(bucketName string, rule *LifecycleRule) error {
+	return client.UpsertLifecycleRuleWithContext(context.Background(), bucketName, rule)
+}
+
+// UpsertLifecycleRuleWithContext adds or replaces rule of lifecycle config of bucket with context controlling
+func (client *Client) UpsertLifecycleRuleWithContext(ctx context.Context, bucketName string, rule *LifecycleRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	return client.updateLifecycleConfig(ctx, bucketName, func(config *LifecycleConfig) error {
+		if i := config.index(rule.ID); i >= 0 {
+			config.Rules[i] = *rule.clone()
+		} else {
+			config.Rules = append(config.Rules, *rule.clone())
+		}
+		return config.validateRule(rule.ID)
+	})
+}
+
+// LifecycleRuleChange is a rule changed by ReconcileLifecycle
+type LifecycleRuleChange struct {
+	From LifecycleRule
+	To   LifecycleRule
+}
+
+// LifecycleDiff is difference between current and desired lifecycle config
+type LifecycleDiff struct {
+	Added   []LifecycleRule
+	Changed []LifecycleRuleChange
+	Removed []LifecycleRule
+}
+
+// Empty tells whether nothing needs to be changed
+func (diff *LifecycleDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Changed) == 0 && len(diff.Removed) == 0
+}
+
+// DiffLifecycle compares current with desired, rules are matched by id. Rules only in current
+// are Removed if prune is true, they are left alone otherwise.
+func DiffLifecycle(current, desired *LifecycleConfig, prune bool) *LifecycleDiff {
+	diff := &LifecycleDiff{}
+	for _, rule := range desired.Rules {
+		i := current.index(rule.ID)
+		if i < 0 {
+			diff.Added = append(diff.Added, rule)
+		} else if !lifecycleRuleEqual(&current.Rules[i], &rule) {
+			diff.Changed = append(diff.Changed, LifecycleRuleChange{From: current.Rules[i], To: rule})
+		}
+	}
+	if prune {
+		for _, rule := range current.Rules {
+			if desired.index(rule.ID) < 0 {
+				diff.Removed = append(diff.Removed, rule)
+			}
+		}
+	}
+	return diff
+}
+
+// apply applies diff to config
+func (diff *LifecycleDiff) apply(config *LifecycleConfig) {
+	for _, rule := range diff.Removed {
+		if i := config.index(rule.ID); i >= 0 {
+			config.Rules = append(config.Rules[:i], config.Rules[i+1:]...)
+		}
+	}
+	for _, change := range diff.Changed {
+		if i := config.index(change.To.ID); i >= 0 {
+			config.Rules[i] = *change.To.clone()
+		}
+	}
+	for _, rule := range diff.Added {
+		config.Rules = append(config.Rules, *rule.clone())
+	}
+}
+
+// ReconcileLifecycleRequest is input of ReconcileLifecycle
+type ReconcileLifecycleRequest struct {
+	BucketName string
+	Desired    *LifecycleConfig
+	// Prune removes rules not in Desired, rules of others are kept if it's false
+	Prune bool
+	// DryRun only computes the diff
+	DryRun bool
+}
+
+// ReconcileLifecycle makes lifecycle config of bucket match Desired, only rules in the
+// returned diff are changed and nothing is written if the diff is empty
+func (client *Client) ReconcileLifecycle(request *ReconcileLifecycleRequest) (*LifecycleDiff, error) {
+	return client.ReconcileLifecycleWithContext(context.Background(), request)
+}
+
+// ReconcileLifecycleWithContext makes lifecycle config of bucket match Desired with context controlling
+func (client *Client) ReconcileLifecycleWithContext(ctx context.Context, request *ReconcileLifecycleRequest) (*LifecycleDiff, error) {
+	if err := request.Desired.Validate(); err != nil {
+		return nil, err
+	}
+
+	var diff *LifecycleDiff
+	err := client.updateLifecycleConfig(ctx, request.BucketName, func(config *LifecycleConfig) error {
+		diff = DiffLifecycle(config, request.Desired, request.Prune)
+		target := config
+		if request.DryRun {
+			target = config.clone()
+		}
+		diff.apply(target)
+
+		// only rules to be written are checked against the others
+		for _, rule := range diff.Added {
+			if err := target.validateRule(rule.ID); err != nil {
+				return err
+			}
+		}
+		for _, change := range diff.Changed {
+			if err := target.validateRule(change.To.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
@@ -0,0 +1,275 @@
+package fds
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleRuleBuilder(t *testing.T) {
+	rule, err := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).AbortIncompleteMultipartUpload(7).Build()
+	assert.Nil(t, err)
+	assert.Equal(t, "logs/", rule.Prefix)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, LifecycleAction{
+		Expiration:                     LifecycleBaseItem{Days: 30},
+		AbortIncompleteMultipartUpload: LifecycleBaseItem{Days: 7},
+	}, rule.Action)
+
+	_, err = NewLifecycleRuleBuilder("").Expire(1).Build()
+	assert.True(t, errors.Is(err, ErrorLifecycleRuleID))
+	_, err = NewLifecycleRuleBuilder("a").Build()
+	assert.True(t, errors.Is(err, ErrorLifecycleNoAction))
+	_, err = NewLifecycleRuleBuilder("a").Expire(0).Build()
+	assert.True(t, errors.Is(err, ErrorLifecycleDays))
+}
+
+func TestLifecycleConfig_Validate(t *testing.T) {
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").Expire(1).Build()
+	all, _ := NewLifecycleRuleBuilder("all").Expire(1).Build()
+	nested, _ := NewLifecycleRuleBuilder("nested").Prefix("logs/app/").Expire(7).Build()
+
+	config := &LifecycleConfig{Rules: []LifecycleRule{*logs, *tmp}}
+	assert.Nil(t, config.Validate())
+
+	config = &LifecycleConfig{Rules: []LifecycleRule{*logs, *logs}}
+	assert.True(t, errors.Is(config.Validate(), ErrorLifecycleRuleID))
+
+	config = &LifecycleConfig{Rules: []LifecycleRule{*logs, *nested}}
+	assert.True(t, errors.Is(config.Validate(), ErrorLifecyclePrefixOverlap))
+
+	nested.Enabled = false
+	config = &LifecycleConfig{Rules: []LifecycleRule{*logs, *nested}}
+	assert.Nil(t, config.Validate())
+
+	// a bucket wide rule overlaps every other rule, it would expire logs/ after 1 day
+	logs.Action[Expiration] = LifecycleBaseItem{Days: 365}
+	config = &LifecycleConfig{Rules: []LifecycleRule{*logs, *all}}
+	assert.True(t, errors.Is(config.Validate(), ErrorLifecyclePrefixOverlap))
+
+	all.Enabled = false
+	config = &LifecycleConfig{Rules: []LifecycleRule{*logs, *all}}
+	assert.Nil(t, config.Validate())
+
+	config = &LifecycleConfig{Rules: []LifecycleRule{{ID: "legacy", Action: LifecycleAction{legacyAbortIncompleteMultipartUpload: {Days: 1}}}}}
+	assert.Nil(t, config.Validate())
+
+	config = &LifecycleConfig{Rules: []LifecycleRule{{ID: "x", Action: LifecycleAction{"unknown": {Days: 1}}}}}
+	assert.True(t, errors.Is(config.Validate(), ErrorLifecycleAction))
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").AbortIncompleteMultipartUpload(1).Build()
+	config := &LifecycleConfig{Rules: []LifecycleRule{*logs, *tmp}}
+
+	e := Evaluate(config, &ObjectSummary{ObjectName: "logs/a", LastModified: now.AddDate(0, 0, -31)}, now)
+	assert.NotNil(t, e)
+	assert.Equal(t, "logs", e.Rule.ID)
+	assert.Equal(t, Expiration, e.Action)
+	assert.True(t, e.Due)
+	assert.Equal(t, now.AddDate(0, 0, -1), e.DueAt)
+
+	uploadTime := now.AddDate(0, 0, -10).UnixNano() / int64(time.Millisecond)
+	e = Evaluate(config, &ObjectSummary{ObjectName: "logs/b", UploadTime: uploadTime}, now)
+	assert.NotNil(t, e)
+	assert.False(t, e.Due)
+
+	assert.Nil(t, Evaluate(config, &ObjectSummary{ObjectName: "tmp/a", LastModified: now}, now))
+	assert.Nil(t, Evaluate(config, &ObjectSummary{ObjectName: "data/a", LastModified: now}, now))
+}
+
+func TestDryRunLifecycle(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -40).Format(time.RFC3339)
+	recent := now.AddDate(0, 0, -1).Format(time.RFC3339)
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("marker") == "" {
+			w.Write([]byte(`{"name":"bucket","truncated":true,"nextMarker":"logs/b","objects":[
+				{"name":"logs/a","size":10,"lastModified":"` + old + `"},
+				{"name":"logs/b","size":20,"lastModified":"` + recent + `"}]}`))
+			return
+		}
+		w.Write([]byte(`{"name":"bucket","truncated":false,"objects":[
+			{"name":"data/c","size":30,"lastModified":"` + old + `"}]}`))
+	})
+
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	report, err := client.DryRunLifecycle(&DryRunLifecycleRequest{
+		BucketName: "bucket",
+		Config:     &LifecycleConfig{Rules: []LifecycleRule{*logs}},
+		Now:        now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), report.Scanned)
+	assert.Equal(t, 2, len(report.Items))
+	assert.Equal(t, "logs/a", report.Items[0].ObjectName)
+	assert.True(t, report.Items[0].Due)
+	assert.Equal(t, &LifecycleRuleStats{Objects: 2, Bytes: 30, DueObjects: 1, DueBytes: 10}, report.Rules["logs"])
+
+	_, err = client.DryRunLifecycle(&DryRunLifecycleRequest{
+		BucketName: "bucket",
+		Config:     &LifecycleConfig{Rules: []LifecycleRule{*logs, *logs}},
+	})
+	assert.True(t, errors.Is(err, ErrorLifecycleRuleID))
+}
+
+// lifecycleServer stores lifecycle config in memory, onGet is called before each read
+type lifecycleServer struct {
+	mu     sync.Mutex
+	config LifecycleConfig
+	gets   int
+	puts   int
+	onGet  func(gets int, config *LifecycleConfig)
+}
+
+func (s *lifecycleServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		s.gets++
+		if s.onGet != nil {
+			s.onGet(s.gets, &s.config)
+		}
+		json.NewEncoder(w).Encode(s.config)
+	case http.MethodPut:
+		s.puts++
+		s.config = LifecycleConfig{}
+		json.NewDecoder(r.Body).Decode(&s.config)
+	}
+}
+
+func newLifecycleServer(t *testing.T, rules ...*LifecycleRule) (*Client, *lifecycleServer) {
+	server := &lifecycleServer{}
+	for _, rule := range rules {
+		server.config.Rules = append(server.config.Rules, *rule)
+	}
+	return newTestClient(t, server.handle), server
+}
+
+func TestDeleteLifecycleRule(t *testing.T) {
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").Expire(1).Build()
+	client, server := newLifecycleServer(t, logs, tmp)
+
+	assert.Nil(t, client.DeleteLifecycleRule("bucket", "logs"))
+	assert.Equal(t, 1, len(server.config.Rules))
+	assert.Equal(t, "tmp", server.config.Rules[0].ID)
+
+	err := client.DeleteLifecycleRule("bucket", "logs")
+	assert.True(t, errors.Is(err, ErrorLifecycleRuleNotFound))
+	assert.Equal(t, 1, server.puts)
+}
+
+func TestUpsertLifecycleRule(t *testing.T) {
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	client, server := newLifecycleServer(t, logs)
+
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").Expire(1).Build()
+	assert.Nil(t, client.UpsertLifecycleRule("bucket", tmp))
+	logs.Action[Expiration] = LifecycleBaseItem{Days: 60}
+	assert.Nil(t, client.UpsertLifecycleRule("bucket", logs))
+	assert.Equal(t, 2, len(server.config.Rules))
+	assert.Equal(t, float64(60), server.config.Rules[0].Action[Expiration].Days)
+
+	// unchanged rule isn't written again
+	assert.Nil(t, client.UpsertLifecycleRule("bucket", logs))
+	assert.Equal(t, 2, server.puts)
+
+	overlap, _ := NewLifecycleRuleBuilder("nested").Prefix("logs/app/").Expire(7).Build()
+	assert.True(t, errors.Is(client.UpsertLifecycleRule("bucket", overlap), ErrorLifecyclePrefixOverlap))
+	all, _ := NewLifecycleRuleBuilder("all").Expire(1).Build()
+	assert.True(t, errors.Is(client.UpsertLifecycleRule("bucket", all), ErrorLifecyclePrefixOverlap))
+	assert.Equal(t, 2, server.puts)
+}
+
+func TestUpdateLifecycleRule_InvalidOthers(t *testing.T) {
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	nested, _ := NewLifecycleRuleBuilder("nested").Prefix("logs/app/").Expire(7).Build()
+	unknown := &LifecycleRule{ID: "unknown", Prefix: "x/", Enabled: true, Action: LifecycleAction{"transition": {Days: 1}}}
+	legacy := &LifecycleRule{ID: "legacy", Prefix: "legacy/", Enabled: true, Action: LifecycleAction{legacyAbortIncompleteMultipartUpload: {Days: 1}}}
+	client, server := newLifecycleServer(t, logs, nested, unknown, legacy)
+
+	// rules already on the bucket don't block changes to others
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").Expire(1).Build()
+	assert.Nil(t, client.UpsertLifecycleRule("bucket", tmp))
+	assert.Nil(t, client.DeleteLifecycleRule("bucket", "logs"))
+	assert.Nil(t, client.DeleteLifecycleRule("bucket", "unknown"))
+	assert.Equal(t, 3, server.puts)
+	assert.Equal(t, 3, len(server.config.Rules))
+
+	diff, err := client.ReconcileLifecycle(&ReconcileLifecycleRequest{
+		BucketName: "bucket",
+		Desired:    &LifecycleConfig{Rules: []LifecycleRule{*logs}},
+	})
+	assert.True(t, errors.Is(err, ErrorLifecyclePrefixOverlap))
+	assert.Nil(t, diff)
+	assert.Equal(t, 3, server.puts)
+}
+
+func TestUpsertLifecycleRule_Conflict(t *testing.T) {
+	client, server := newLifecycleServer(t)
+	server.onGet = func(gets int, config *LifecycleConfig) {
+		// another writer changes config between every read and write
+		rule, _ := NewLifecycleRuleBuilder(strconv.Itoa(gets)).Prefix(strconv.Itoa(gets) + "/").Expire(1).Build()
+		config.Rules = append(config.Rules, *rule)
+	}
+
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").Expire(1).Build()
+	assert.Equal(t, ErrorLifecycleConflict, client.UpsertLifecycleRule("bucket", tmp))
+	assert.Equal(t, 0, server.puts)
+
+	server.onGet = func(gets int, config *LifecycleConfig) {
+		// tmp is added between the read and re-read of the first attempt
+		if gets == 8 {
+			config.Rules = append(config.Rules, *tmp)
+		}
+	}
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	assert.Nil(t, client.UpsertLifecycleRule("bucket", logs))
+	assert.Equal(t, 10, server.gets)
+	assert.Equal(t, 1, server.puts)
+	assert.NotEqual(t, -1, server.config.index("tmp"))
+	assert.NotEqual(t, -1, server.config.index("logs"))
+}
+
+func TestReconcileLifecycle(t *testing.T) {
+	logs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(30).Build()
+	tmp, _ := NewLifecycleRuleBuilder("tmp").Prefix("tmp/").Expire(1).Build()
+	other, _ := NewLifecycleRuleBuilder("other").Prefix("other/").Expire(90).Build()
+	client, server := newLifecycleServer(t, logs, other)
+
+	newLogs, _ := NewLifecycleRuleBuilder("logs").Prefix("logs/").Expire(60).Build()
+	desired := &LifecycleConfig{Rules: []LifecycleRule{*newLogs, *tmp}}
+
+	diff, err := client.ReconcileLifecycle(&ReconcileLifecycleRequest{BucketName: "bucket", Desired: desired, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []LifecycleRule{*tmp}, diff.Added)
+	assert.Equal(t, 1, len(diff.Changed))
+	assert.Equal(t, float64(30), diff.Changed[0].From.Action[Expiration].Days)
+	assert.Nil(t, diff.Removed)
+	assert.Equal(t, 0, server.puts)
+
+	diff, err = client.ReconcileLifecycle(&ReconcileLifecycleRequest{BucketName: "bucket", Desired: desired, Prune: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []LifecycleRule{*other}, diff.Removed)
+	assert.Equal(t, 1, server.puts)
+	assert.Equal(t, 2, len(server.config.Rules))
+	assert.Equal(t, -1, server.config.index("other"))
+
+	diff, err = client.ReconcileLifecycle(&ReconcileLifecycleRequest{BucketName: "bucket", Desired: desired, Prune: true})
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+	assert.Equal(t, 1, server.puts)
+}
@@ -0,0 +1,199 @@
+package fds
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Log field names
+const (
+	LogFieldOperation = "operation"
+	LogFieldBucket    = "bucket"
+	LogFieldObject    = "object"
+	LogFieldMethod    = "method"
+	LogFieldURL       = "url"
+	LogFieldHeader    = "header"
+	LogFieldStatus    = "status"
+	LogFieldLatency   = "latency"
+	LogFieldAttempt   = "attempt"
+	LogFieldRequestID = "request_id"
+	LogFieldError     = "error"
+	LogFieldClockSkew = "clock_skew"
+)
+
+// redacted replaces secrets in logs
+const redacted = "REDACTED"
+
+// Fields is structured fields of a log entry
+type Fields map[string]interface{}
+
+// Logger is the logging interface of Client and manager
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+// NewLogrusLogger makes a Logger writing into logger
+func NewLogrusLogger(logger *logrus.Logger) Logger {
+	return &logrusLogger{logger}
+}
+
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+func (l *logrusLogger) Debug(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Debug(msg)
+}
+
+func (l *logrusLogger) Info(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Warn(msg)
+}
+
+func (l *logrusLogger) Error(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Error(msg)
+}
+
+// LogLevel is level of StdLogger
+type LogLevel int
+
+// LogLevel const
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogLevelDebug: "DEBUG",
+	LogLevelInfo:  "INFO",
+	LogLevelWarn:  "WARN",
+	LogLevelError: "ERROR",
+}
+
+// NewStdLogger makes a Logger writing entries not lower than level into logger of
+// standard library, fields are written as sorted key=value pairs
+func NewStdLogger(logger *log.Logger, level LogLevel) Logger {
+	return &stdLogger{logger: logger, level: level}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+func (l *stdLogger) log(level LogLevel, msg string, fields Fields) {
+	if level < l.level {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	buf.WriteString(logLevelNames[level])
+	buf.WriteString(" ")
+	buf.WriteString(msg)
+	for _, k := range keys {
+		buf.WriteString(fmt.Sprintf(" %s=%v", k, fields[k]))
+	}
+	l.logger.Print(buf.String())
+}
+
+func (l *stdLogger) Debug(msg string, fields Fields) {
+	l.log(LogLevelDebug, msg, fields)
+}
+
+func (l *stdLogger) Info(msg string, fields Fields) {
+	l.log(LogLevelInfo, msg, fields)
+}
+
+func (l *stdLogger) Warn(msg string, fields Fields) {
+	l.log(LogLevelWarn, msg, fields)
+}
+
+func (l *stdLogger) Error(msg string, fields Fields) {
+	l.log(LogLevelError, msg, fields)
+}
+
+// NopLogger is a Logger discarding everything
+type NopLogger struct{}
+
+// Debug does nothing
+func (NopLogger) Debug(msg string, fields Fields) {}
+
+// Info does nothing
+func (NopLogger) Info(msg string, fields Fields) {}
+
+// Warn does nothing
+func (NopLogger) Warn(msg string, fields Fields) {}
+
+// Error does nothing
+func (NopLogger) Error(msg string, fields Fields) {}
+
+// newDefaultLogger is a logrus logger of warn level
+func newDefaultLogger() Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	return NewLogrusLogger(logger)
+}
+
+// withFields merges fields into a new Fields
+func withFields(base Fields, fields Fields) Fields {
+	result := make(Fields, len(base)+len(fields))
+	for k, v := range base {
+		result[k] = v
+	}
+	for k, v := range fields {
+		result[k] = v
+	}
+	return result
+}
+
+// redactHeader copies header without secrets
+func redactHeader(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for k, v := range header {
+		result[k] = v
+	}
+
+	if auth := header.Get(HTTPHeaderAuthorization); auth != "" {
+		// Galaxy-V2 AccessID:Signature
+		if i := strings.LastIndex(auth, ":"); i != -1 {
+			auth = auth[:i+1] + redacted
+		} else {
+			auth = redacted
+		}
+		result.Set(HTTPHeaderAuthorization, auth)
+	}
+	return result
+}
+
+// redactURL makes a string of u without signature
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	if _, ok := query[HTTPHeaderSignature]; !ok {
+		return u.String()
+	}
+
+	query.Set(HTTPHeaderSignature, redacted)
+	c := *u
+	c.RawQuery = query.Encode()
+	return c.String()
+}
@@ -0,0 +1,91 @@
+package fds
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields Fields
+}
+
+type recordLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordLogger) record(level, msg string, fields Fields) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{level, msg, fields})
+}
+
+func (l *recordLogger) Debug(msg string, fields Fields) { l.record("debug", msg, fields) }
+func (l *recordLogger) Info(msg string, fields Fields)  { l.record("info", msg, fields) }
+func (l *recordLogger) Warn(msg string, fields Fields)  { l.record("warn", msg, fields) }
+func (l *recordLogger) Error(msg string, fields Fields) { l.record("error", msg, fields) }
+
+func TestLogger_Client(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HTTPHeaderRequestID, "request-1")
+		if r.URL.Path == "/bucket/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("content"))
+	})
+	logger := &recordLogger{}
+	client.logger = logger
+
+	reader, err := client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	reader.Close()
+
+	assert.Len(t, logger.entries, 2)
+	request := logger.entries[0]
+	assert.Equal(t, "GetObject", request.fields[LogFieldOperation])
+	assert.Equal(t, "bucket", request.fields[LogFieldBucket])
+	assert.Equal(t, "object", request.fields[LogFieldObject])
+	header := request.fields[LogFieldHeader].(http.Header)
+	assert.True(t, strings.HasSuffix(header.Get(HTTPHeaderAuthorization), "ak:"+redacted))
+
+	response := logger.entries[1]
+	assert.Equal(t, http.StatusOK, response.fields[LogFieldStatus])
+	assert.Equal(t, "request-1", response.fields[LogFieldRequestID])
+	assert.NotNil(t, response.fields[LogFieldLatency])
+
+	_, err = client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "missing"})
+	assert.NotNil(t, err)
+	failed := logger.entries[len(logger.entries)-1]
+	assert.Equal(t, "info", failed.level)
+	assert.Equal(t, http.StatusNotFound, failed.fields[LogFieldStatus])
+	assert.Equal(t, err, failed.fields[LogFieldError])
+
+	// operation is the Client method even if it's called in a closure
+	func() { client.DeleteObject("bucket", "object") }()
+	assert.Equal(t, "DeleteObject", logger.entries[len(logger.entries)-1].fields[LogFieldOperation])
+}
+
+func TestLogger_StdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LogLevelInfo)
+
+	logger.Debug("hidden", nil)
+	logger.Info("request", Fields{LogFieldStatus: 200, LogFieldBucket: "b"})
+	assert.Equal(t, "INFO request bucket=b status=200\n", buf.String())
+}
+
+func TestLogger_RedactURL(t *testing.T) {
+	u, _ := url.Parse("http://cnbj0.fds.api.xiaomi.com/b/o?GalaxyAccessKeyId=ak&Expires=1&Signature=secret")
+	assert.NotContains(t, redactURL(u), "secret")
+	assert.Contains(t, redactURL(u), "GalaxyAccessKeyId=ak")
+}
@@ -0,0 +1,326 @@
+package manager
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"golang.org/x/time/rate"
+)
+
+// ACLManager applies ACL changes to and audits ACL of all objects under a prefix concurrently
+type ACLManager struct {
+	logger  fds.Logger
+	client  *fds.Client
+	limiter *rate.Limiter
+
+	Concurrency int
+}
+
+// NewACLManager new an ACLManager
+func NewACLManager(client *fds.Client, concurrency int) (*ACLManager, error) {
+	if concurrency < 1 {
+		return nil, ErrorConcurrencySmallerThanOne
+	}
+
+	return &ACLManager{
+		Concurrency: concurrency,
+
+		client: client,
+		logger: client.Logger(),
+	}, nil
+}
+
+// SetLimiter sets a limiter shared by all workers, each object takes a token
+func (manager *ACLManager) SetLimiter(limiter *rate.Limiter) {
+	manager.limiter = limiter
+}
+
+// SetLogger sets logger of manager, logger of client is used by default
+func (manager *ACLManager) SetLogger(logger fds.Logger) {
+	manager.logger = logger
+}
+
+// walkObjects lists objects of bucket under prefix and calls fn for each of them by
+// concurrency workers, listing error is returned after workers finish
+func walkObjects(ctx context.Context, client *fds.Client, bucketName, prefix string, concurrency int,
+	limiter *rate.Limiter, fn func(ctx context.Context, object *fds.ObjectSummary)) error {
+	objects := make(chan *fds.ObjectSummary)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objects {
+				if limiter != nil {
+					if err := limiter.Wait(ctx); err != nil {
+						continue
+					}
+				}
+				fn(ctx, object)
+			}
+		}()
+	}
+
+	err := func() error {
+		listing, err := client.ListObjectsWithContext(ctx, &fds.ListObjectsRequest{
+			BucketName: bucketName,
+			Prefix:     prefix,
+		})
+		for {
+			if err != nil {
+				return err
+			}
+			for i := range listing.ObjectSummaries {
+				select {
+				case objects <- &listing.ObjectSummaries[i]:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if !listing.Truncated {
+				return nil
+			}
+			listing, err = client.ListObjectsNextBatchWithContext(ctx, listing)
+		}
+	}()
+	close(objects)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// ACLTransform turns acl, a copy of current ACL of object, into the desired ACL
+type ACLTransform func(object *fds.ObjectSummary, acl *fds.AccessControlList) *fds.AccessControlList
+
+// ApplyACLRequest is input of ApplyACL
+type ApplyACLRequest struct {
+	BucketName string
+	Prefix     string
+	Transform  ACLTransform
+	// DryRun only computes the diff of each object
+	DryRun bool
+}
+
+// ACLResult is result of an object changed or failed in ApplyACL
+type ACLResult struct {
+	ObjectName string
+	Diff       *fds.ACLDiff
+	Err        error
+}
+
+// ApplyACLReport is result of ApplyACL
+type ApplyACLReport struct {
+	Scanned int64
+	Changed int64
+	Failed  int64
+	// Results has objects changed or failed only, in no particular order
+	Results []ACLResult
+}
+
+// ApplyACL changes ACL of every object under Prefix by Transform, only the
+// difference is sent for each object
+func (manager *ACLManager) ApplyACL(request *ApplyACLRequest) (*ApplyACLReport, error) {
+	return manager.ApplyACLWithContext(context.Background(), request)
+}
+
+// ApplyACLWithContext changes ACL of every object under Prefix with context controlling
+func (manager *ACLManager) ApplyACLWithContext(ctx context.Context, request *ApplyACLRequest) (*ApplyACLReport, error) {
+	ctx, span := fds.StartSpan(ctx, manager.client.Configuration.Tracer, "fds.ApplyACL")
+	span.SetTag(fds.TraceTagBucket, request.BucketName)
+
+	var mu sync.Mutex
+	report := &ApplyACLReport{}
+
+	err := walkObjects(ctx, manager.client, request.BucketName, request.Prefix, manager.Concurrency, manager.limiter,
+		func(ctx context.Context, object *fds.ObjectSummary) {
+			diff, err := manager.client.UpdateObjectACLWithContext(ctx, &fds.UpdateObjectACLRequest{
+				BucketName: request.BucketName,
+				ObjectName: object.ObjectName,
+				Update: func(acl *fds.AccessControlList) *fds.AccessControlList {
+					return request.Transform(object, acl)
+				},
+				DryRun: request.DryRun,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Scanned++
+			switch {
+			case err != nil:
+				report.Failed++
+				report.Results = append(report.Results, ACLResult{ObjectName: object.ObjectName, Err: err})
+				manager.logger.Warn("failed to apply acl", fds.Fields{
+					fds.LogFieldOperation: "ApplyACL",
+					fds.LogFieldBucket:    request.BucketName,
+					fds.LogFieldObject:    object.ObjectName,
+					fds.LogFieldError:     err,
+				})
+			case !diff.Empty():
+				report.Changed++
+				report.Results = append(report.Results, ACLResult{ObjectName: object.ObjectName, Diff: diff})
+			}
+		})
+	fds.FinishSpan(span, err)
+	return report, err
+}
+
+// ExposureKind is kind of an ExposureFinding
+type ExposureKind string
+
+// ExposureKind const
+const (
+	// ExposurePublic is a grant to ALL_USERS or AUTHENTICATED_USERS
+	ExposurePublic ExposureKind = "public"
+	// ExposureCrossTenant is a grant to a user who is neither owner nor trusted
+	ExposureCrossTenant ExposureKind = "cross-tenant"
+)
+
+// ExposureFinding is a risky grant found by Audit, ObjectName is empty for a bucket grant
+type ExposureFinding struct {
+	BucketName  string              `json:"bucketName"`
+	ObjectName  string              `json:"objectName,omitempty"`
+	Kind        ExposureKind        `json:"kind"`
+	GranteeID   string              `json:"granteeId"`
+	GranteeType fds.GrantType       `json:"granteeType"`
+	Permission  fds.GrantPermission `json:"permission"`
+}
+
+// AuditRequest is input of Audit
+type AuditRequest struct {
+	BucketNames []string
+	// Prefix limits objects audited in each bucket
+	Prefix string
+	// TrustedIDs are users of the same tenant, grants to them or owner are not reported
+	TrustedIDs []string
+	// SkipObjects audits ACL of buckets only
+	SkipObjects bool
+}
+
+// AuditError is an ACL failed to be read in Audit
+type AuditError struct {
+	BucketName string `json:"bucketName"`
+	ObjectName string `json:"objectName,omitempty"`
+	Err        error  `json:"-"`
+	Message    string `json:"error"`
+}
+
+// AuditReport is result of Audit
+type AuditReport struct {
+	ScannedBuckets int64             `json:"scannedBuckets"`
+	ScannedObjects int64             `json:"scannedObjects"`
+	Findings       []ExposureFinding `json:"findings"`
+	Errors         []AuditError      `json:"errors,omitempty"`
+}
+
+// Audit reports buckets and objects with public or cross-tenant grants, no ACL is changed.
+// Failures of single ACL are put into Errors of report and don't stop auditing.
+func (manager *ACLManager) Audit(request *AuditRequest) (*AuditReport, error) {
+	return manager.AuditWithContext(context.Background(), request)
+}
+
+// AuditWithContext reports buckets and objects with public or cross-tenant grants with context controlling
+func (manager *ACLManager) AuditWithContext(ctx context.Context, request *AuditRequest) (*AuditReport, error) {
+	ctx, span := fds.StartSpan(ctx, manager.client.Configuration.Tracer, "fds.Audit")
+
+	trusted := make(map[string]bool, len(request.TrustedIDs))
+	for _, id := range request.TrustedIDs {
+		trusted[id] = true
+	}
+
+	var mu sync.Mutex
+	report := &AuditReport{}
+	record := func(bucketName, objectName string, acl *fds.AccessControlList, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			report.Errors = append(report.Errors, AuditError{
+				BucketName: bucketName,
+				ObjectName: objectName,
+				Err:        err,
+				Message:    err.Error(),
+			})
+			return
+		}
+		report.Findings = append(report.Findings, exposures(bucketName, objectName, acl, trusted)...)
+	}
+
+	for _, bucketName := range request.BucketNames {
+		acl, err := manager.client.GetBucketACLWithContext(ctx, bucketName)
+		report.ScannedBuckets++
+		record(bucketName, "", acl, err)
+
+		if request.SkipObjects || err != nil {
+			continue
+		}
+		err = walkObjects(ctx, manager.client, bucketName, request.Prefix, manager.Concurrency, manager.limiter,
+			func(ctx context.Context, object *fds.ObjectSummary) {
+				acl, err := manager.client.GetObjectACLWithContext(ctx, &fds.GetObjectACLRequest{
+					BucketName: bucketName,
+					ObjectName: object.ObjectName,
+				})
+				mu.Lock()
+				report.ScannedObjects++
+				mu.Unlock()
+				record(bucketName, object.ObjectName, acl, err)
+			})
+		if err != nil && ctx.Err() == nil {
+			// listing of this bucket failed, others are still audited
+			record(bucketName, "", nil, err)
+		}
+	}
+
+	err := ctx.Err()
+	fds.FinishSpan(span, err)
+	return report, err
+}
+
+// exposures returns risky grants of acl
+func exposures(bucketName, objectName string, acl *fds.AccessControlList, trusted map[string]bool) []ExposureFinding {
+	var findings []ExposureFinding
+	for _, g := range acl.Grants {
+		var kind ExposureKind
+		switch {
+		case g.Grantee.ID == fds.AllUsers.ID || g.Grantee.ID == fds.AuthenticatedUsers.ID:
+			kind = ExposurePublic
+		case g.Type == fds.GrantTypeUser && g.Grantee.ID != acl.Owner.ID && !trusted[g.Grantee.ID]:
+			kind = ExposureCrossTenant
+		default:
+			continue
+		}
+		findings = append(findings, ExposureFinding{
+			BucketName:  bucketName,
+			ObjectName:  objectName,
+			Kind:        kind,
+			GranteeID:   g.Grantee.ID,
+			GranteeType: g.Type,
+			Permission:  g.Permission,
+		})
+	}
+	return findings
+}
+
+// WriteJSON writes report as indented JSON
+func (report *AuditReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteCSV writes findings of report as CSV with a header line
+func (report *AuditReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"bucket", "object", "kind", "grantee_id", "grantee_type", "permission"})
+	for _, f := range report.Findings {
+		writer.Write([]string{f.BucketName, f.ObjectName, string(f.Kind), f.GranteeID, string(f.GranteeType), string(f.Permission)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/stretchr/testify/assert"
+)
+
+// aclStore serves ListObjects and ACL requests of bucket in the hook of fakeServer
+type aclStore struct {
+	mu   sync.Mutex
+	acls map[string]*fds.AccessControlList
+}
+
+func (s *aclStore) hook(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	if _, ok := query["acl"]; ok {
+		acl, ok := s.acls[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return false
+		}
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(acl)
+			return false
+		}
+		changes := &fds.AccessControlList{}
+		json.NewDecoder(r.Body).Decode(changes)
+		for _, g := range changes.Grants {
+			if query.Get("action") == "delete" {
+				acl.Revoke(g)
+			} else {
+				acl.Grant(g)
+			}
+		}
+		return false
+	}
+
+	// ListObjects returns one object per page
+	var names []string
+	for name := range s.acls {
+		if strings.HasPrefix(name, path+"/"+query.Get("prefix")) && name > path+"/"+query.Get("marker") {
+			names = append(names, strings.TrimPrefix(name, path+"/"))
+		}
+	}
+	sort.Strings(names)
+	listing := &fds.ObjectListing{BucketName: path, Prefix: query.Get("prefix")}
+	if len(names) > 0 {
+		listing.ObjectSummaries = []fds.ObjectSummary{{ObjectName: names[0]}}
+		listing.Truncated = len(names) > 1
+		listing.NextMarker = names[0]
+	}
+	json.NewEncoder(w).Encode(listing)
+	return false
+}
+
+func newACLStore(t *testing.T) (*fakeServer, *aclStore) {
+	owner := fds.Owner{ID: "owner"}
+	store := &aclStore{acls: map[string]*fds.AccessControlList{
+		"bucket":     {Owner: owner, Grants: []fds.Grant{fds.NewUserGrant("owner", fds.GrantPermissionFullControl)}},
+		"bucket/a/1": {Owner: owner, Grants: []fds.Grant{fds.NewGroupGrant(fds.AllUsers, fds.GrantPermissionRead)}},
+		"bucket/a/2": {Owner: owner, Grants: []fds.Grant{fds.NewUserGrant("partner", fds.GrantPermissionRead)}},
+		"bucket/a/3": {Owner: owner, Grants: []fds.Grant{fds.NewUserGrant("stranger", fds.GrantPermissionWrite)}},
+		"bucket/b/1": {Owner: owner, Grants: []fds.Grant{fds.NewGroupGrant(fds.AuthenticatedUsers, fds.GrantPermissionRead)}},
+	}}
+	server := newFakeServer(t)
+	server.hook = store.hook
+	return server, store
+}
+
+func TestACLManager_ApplyACL(t *testing.T) {
+	server, store := newACLStore(t)
+	manager, err := NewACLManager(server.Client(), 2)
+	assert.Nil(t, err)
+
+	request := &ApplyACLRequest{
+		BucketName: "bucket",
+		Prefix:     "a/",
+		Transform: func(object *fds.ObjectSummary, acl *fds.AccessControlList) *fds.AccessControlList {
+			return acl.MakePrivate()
+		},
+		DryRun: true,
+	}
+	report, err := manager.ApplyACL(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), report.Scanned)
+	assert.Equal(t, int64(1), report.Changed)
+	assert.Equal(t, "a/1", report.Results[0].ObjectName)
+	assert.True(t, store.acls["bucket/a/1"].IsPublic())
+
+	request.DryRun = false
+	report, err = manager.ApplyACL(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), report.Changed)
+	assert.False(t, store.acls["bucket/a/1"].IsPublic())
+	assert.Equal(t, fds.AuthenticatedUsers.ID, store.acls["bucket/b/1"].Grants[0].Grantee.ID)
+
+	_, err = NewACLManager(server.Client(), 0)
+	assert.Equal(t, ErrorConcurrencySmallerThanOne, err)
+}
+
+func TestACLManager_Audit(t *testing.T) {
+	server, _ := newACLStore(t)
+	manager, _ := NewACLManager(server.Client(), 3)
+
+	report, err := manager.Audit(&AuditRequest{
+		BucketNames: []string{"bucket", "missing"},
+		TrustedIDs:  []string{"partner"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), report.ScannedBuckets)
+	assert.Equal(t, int64(4), report.ScannedObjects)
+	assert.Equal(t, 1, len(report.Errors))
+	assert.Equal(t, "missing", report.Errors[0].BucketName)
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		return report.Findings[i].ObjectName < report.Findings[j].ObjectName
+	})
+	assert.Equal(t, []ExposureFinding{
+		{BucketName: "bucket", ObjectName: "a/1", Kind: ExposurePublic, GranteeID: "ALL_USERS", GranteeType: fds.GrantTypeGroup, Permission: fds.GrantPermissionRead},
+		{BucketName: "bucket", ObjectName: "a/3", Kind: ExposureCrossTenant, GranteeID: "stranger", GranteeType: fds.GrantTypeUser, Permission: fds.GrantPermissionWrite},
+		{BucketName: "bucket", ObjectName: "b/1", Kind: ExposurePublic, GranteeID: "AUTHENTICATED_USERS", GranteeType: fds.GrantTypeGroup, Permission: fds.GrantPermissionRead},
+	}, report.Findings)
+
+	var buf bytes.Buffer
+	assert.Nil(t, report.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "bucket,a/3,cross-tenant,stranger,USER,WRITE", lines[2])
+
+	buf.Reset()
+	assert.Nil(t, report.WriteJSON(&buf))
+	decoded := &AuditReport{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), decoded))
+	assert.Equal(t, report.Findings, decoded.Findings)
+	assert.NotEmpty(t, decoded.Errors[0].Message)
+}
@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+)
+
+// checkpointVersion is bumped when format of checkpoint file changes
+const checkpointVersion = 1
+
+// CheckpointFileSuffix is appended to FilePath as default checkpoint file path
+const CheckpointFileSuffix = ".download.bp"
+
+// checkpoint is the header of a checkpoint file, it's written atomically once
+// per download, and each finished part is appended as a partRecord line after it.
+type checkpoint struct {
+	Version    int        `json:"version"`
+	FilePath   string     `json:"filePath"`
+	BucketName string     `json:"bucketName"`
+	ObjectName string     `json:"objectName"`
+	ObjectStat objectStat `json:"objectStat"`
+	Start      int64      `json:"start"`
+	End        int64      `json:"end"`
+	Ranges     string     `json:"ranges,omitempty"`
+	PartSize   int64      `json:"partSize"`
+	MD5        string     `json:"md5"`
+}
+
+// objectStat pins the object version which parts are downloaded from
+type objectStat struct {
+	Size         int64  `json:"size"`
+	LastModified string `json:"lastModified"`
+	ETag         string `json:"etag,omitempty"`
+	CRC64        string `json:"crc64,omitempty"`
+}
+
+func newObjectStat(size int64, metadata *fds.ObjectMetadata) objectStat {
+	return objectStat{
+		Size:         size,
+		LastModified: metadata.Get(fds.HTTPHeaderLastModified),
+		ETag:         metadata.Get(fds.HTTPHeaderETag),
+		CRC64:        metadata.Get(fds.HTTPHeaderCRC64ECMA),
+	}
+}
+
+// partRecord is a finished part with CRC32 of its content
+type partRecord struct {
+	Index int    `json:"index"`
+	Size  int64  `json:"size"`
+	CRC32 uint32 `json:"crc32"`
+}
+
+func newCheckpoint(request *DownloadRequest, stat objectStat, r httpparser.HTTPRange, partSize int64) *checkpoint {
+	return &checkpoint{
+		Version:    checkpointVersion,
+		FilePath:   request.FilePath,
+		BucketName: request.BucketName,
+		ObjectName: request.ObjectName,
+		ObjectStat: stat,
+		Start:      r.Start,
+		End:        r.End,
+		PartSize:   partSize,
+	}
+}
+
+func (cp *checkpoint) sum() (string, error) {
+	c := *cp
+	c.MD5 = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// validate checks whether saved checkpoint is made for the same download as cp
+func (cp *checkpoint) validate(saved *checkpoint) error {
+	sum, err := saved.sum()
+	if err != nil {
+		return err
+	}
+	if sum != saved.MD5 {
+		return ErrorMD5NotMatching
+	}
+
+	if saved.BucketName != cp.BucketName || saved.ObjectName != cp.ObjectName {
+		return ErrorBucketOrObjectNotMatching
+	}
+
+	if saved.Version != cp.Version || saved.ObjectStat != cp.ObjectStat {
+		return ErrorObjectStateNotMatching
+	}
+
+	if saved.Start != cp.Start || saved.End != cp.End || saved.Ranges != cp.Ranges || saved.PartSize != cp.PartSize {
+		return ErrorRangeNotMatching
+	}
+
+	return nil
+}
+
+// loadCheckpoint reads header and part records from path, a torn record at the
+// end of file is ignored
+func loadCheckpoint(path string) (*checkpoint, []partRecord, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() {
+		return nil, nil, ErrorMD5NotMatching
+	}
+
+	saved := &checkpoint{}
+	if err := json.Unmarshal(scanner.Bytes(), saved); err != nil {
+		return nil, nil, err
+	}
+
+	var records []partRecord
+	for scanner.Scan() {
+		var record partRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+
+	return saved, records, nil
+}
+
+// verifyParts returns records whose content in file still matches its CRC32
+func verifyParts(file *os.File, parts []part, records []partRecord) []partRecord {
+	var verified []partRecord
+	for _, record := range records {
+		if record.Index < 0 || record.Index >= len(parts) {
+			continue
+		}
+
+		p := parts[record.Index]
+		if record.Size != p.End-p.Start+1 {
+			continue
+		}
+
+		h := crc32.NewIEEE()
+		_, err := io.Copy(h, io.NewSectionReader(file, p.Start-p.Offset, record.Size))
+		if err != nil || h.Sum32() != record.CRC32 {
+			continue
+		}
+		verified = append(verified, record)
+	}
+	return verified
+}
+
+// checkpointJournal appends finished parts to checkpoint file
+type checkpointJournal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// createCheckpoint writes header of cp and records into path atomically, and
+// opens it for appending
+func createCheckpoint(path string, cp *checkpoint, records []partRecord) (*checkpointJournal, error) {
+	sum, err := cp.sum()
+	if err != nil {
+		return nil, err
+	}
+	cp.MD5 = sum
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	if err := encoder.Encode(cp); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := writeFileSync(tmpPath, buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, fds.FilePermMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkpointJournal{
+		path: path,
+		file: file,
+	}, nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fds.FilePermMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Append records a finished part
+func (j *checkpointJournal) Append(record partRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes checkpoint file, and removes it if download is finished
+func (j *checkpointJournal) Close(finished bool) error {
+	err := j.file.Close()
+	if finished {
+		return os.Remove(j.path)
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/stretchr/testify/assert"
+)
+
+// interruptedDownload downloads object and cancels it after served parts are served
+func interruptedDownload(t *testing.T, server *fakeServer, request *DownloadRequest, served int32) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var count int32
+	server.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get(fds.HTTPHeaderRange) == "" {
+			return true
+		}
+		if atomic.AddInt32(&count, 1) > served {
+			cancel()
+			return false
+		}
+		return true
+	}
+	defer func() { server.hook = nil }()
+
+	downloader, err := NewDownloader(server.Client(), 10, 1, true)
+	assert.Nil(t, err)
+	err = downloader.DownloadWithContext(ctx, request)
+	assert.NotNil(t, err)
+}
+
+func countRangeRequests(server *fakeServer) *int32 {
+	var count int32
+	server.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get(fds.HTTPHeaderRange) != "" {
+			atomic.AddInt32(&count, 1)
+		}
+		return true
+	}
+	return &count
+}
+
+func TestDownloader_Resume(t *testing.T) {
+	server := newFakeServer(t)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10)
+	server.PutObject("bucket", "object", content)
+
+	request := &DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+		},
+		FilePath: filepath.Join(t.TempDir(), "object"),
+	}
+	interruptedDownload(t, server, request, 6)
+
+	_, err := os.Stat(request.FilePath + CheckpointFileSuffix)
+	assert.Nil(t, err)
+	_, records, err := loadCheckpoint(request.FilePath + CheckpointFileSuffix)
+	assert.Nil(t, err)
+	assert.Len(t, records, 6)
+
+	count := countRangeRequests(server)
+	downloader, err := NewDownloader(server.Client(), 10, 3, true)
+	assert.Nil(t, err)
+	err = downloader.Download(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(10), atomic.LoadInt32(count))
+
+	data, err := ioutil.ReadFile(request.FilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, data)
+
+	_, err = os.Stat(request.FilePath + CheckpointFileSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloader_ResumeCorruptedPart(t *testing.T) {
+	server := newFakeServer(t)
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	server.PutObject("bucket", "object", content)
+
+	dir := t.TempDir()
+	request := &DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+		},
+		FilePath:           filepath.Join(dir, "object"),
+		CheckpointFilePath: filepath.Join(dir, "checkpoint"),
+	}
+	interruptedDownload(t, server, request, 5)
+
+	// corrupt the third part and append a torn record
+	tmp, err := os.OpenFile(request.FilePath+".tmp", os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	tmp.WriteAt([]byte("x"), 25)
+	tmp.Close()
+	cp, err := os.OpenFile(request.CheckpointFilePath, os.O_WRONLY|os.O_APPEND, 0)
+	assert.Nil(t, err)
+	cp.Write([]byte(`{"index":7,"si`))
+	cp.Close()
+
+	count := countRangeRequests(server)
+	downloader, err := NewDownloader(server.Client(), 10, 2, true)
+	assert.Nil(t, err)
+	err = downloader.Download(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(6), atomic.LoadInt32(count))
+
+	data, err := ioutil.ReadFile(request.FilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestDownloader_ResumeObjectChanged(t *testing.T) {
+	server := newFakeServer(t)
+	server.PutObject("bucket", "object", bytes.Repeat([]byte("a"), 100))
+
+	request := &DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+		},
+		FilePath: filepath.Join(t.TempDir(), "object"),
+	}
+	interruptedDownload(t, server, request, 4)
+
+	content := bytes.Repeat([]byte("b"), 100)
+	server.PutObject("bucket", "object", content)
+	server.objects["bucket/object"].etag = "changed"
+
+	count := countRangeRequests(server)
+	downloader, err := NewDownloader(server.Client(), 10, 2, true)
+	assert.Nil(t, err)
+	err = downloader.Download(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(10), atomic.LoadInt32(count))
+
+	data, err := ioutil.ReadFile(request.FilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestDownloadJob_CompleteAppendFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "download.cp")
+	journal, err := createCheckpoint(path, &checkpoint{Version: checkpointVersion}, nil)
+	assert.Nil(t, err)
+	journal.file.Close()
+
+	job := &downloadJob{breakpoint: journal}
+	job.complete(part{Index: 0}, 10, 0)
+	assert.True(t, job.failed())
+	assert.Equal(t, int64(10), job.written)
+}
@@ -1,5 +1,7 @@
 /*
-Package manager provides a concurrent downloading api with checkpoint for FDS
+Package manager provides concurrent jobs for FDS, e.g. downloading with checkpoint,
+applying or auditing ACL of all objects under a prefix, making inventory of buckets and
+replicating objects between buckets or regions
 
 */
 package manager
@@ -2,14 +2,13 @@ package manager
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"io"
-	"io/ioutil"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/XiaoMi/go-fds/fds"
 	"github.com/XiaoMi/go-fds/fds/httpparser"
@@ -19,9 +18,10 @@ import (
 
 // Downloader is a FDS client for file concurrency download
 type Downloader struct {
-	logger  *logrus.Logger
-	client  *fds.Client
-	limiter *rate.Limiter
+	logger   fds.Logger
+	client   *fds.Client
+	limiter  *rate.Limiter
+	progress fds.ProgressListener
 
 	PartSize    int64
 	Concurrency int
@@ -44,9 +44,8 @@ func NewDownloader(client *fds.Client, partSize int64, concurrency int, breakpoi
 		Breakpoint:  breakpoint,
 
 		client: client,
+		logger: client.Logger(),
 	}
-	downloader.logger = logrus.New()
-	downloader.logger.SetLevel(logrus.WarnLevel)
 
 	return downloader, nil
 }
@@ -56,8 +55,24 @@ type DownloadRequest struct {
 	fds.GetObjectRequest
 	FilePath string
 
-	// private
-	breakpointFilePath string
+	// WriterAt receives the content instead of FilePath if it is set, content
+	// of the requested range is written from offset 0. Breakpoint is not
+	// available for WriterAt.
+	//
+	// If Range has more than one range, e.g. bytes=0-99,1000-1099, each range is
+	// written at its offset in object, and the file is sparse.
+	WriterAt io.WriterAt
+
+	// CheckpointFilePath is where breakpoint info is saved,
+	// FilePath + CheckpointFileSuffix is used if it's empty
+	CheckpointFilePath string
+}
+
+// DownloadResult is the result of each request in DownloadBatch
+type DownloadResult struct {
+	Request *DownloadRequest
+	Size    int64
+	Err     error
 }
 
 // Download performs the downloading action
@@ -67,336 +82,556 @@ func (downloader *Downloader) Download(request *DownloadRequest) error {
 
 // DownloadWithContext performs the downloading action with context controlling
 func (downloader *Downloader) DownloadWithContext(ctx context.Context, request *DownloadRequest) error {
-	if downloader.Breakpoint && request.breakpointFilePath != "" {
-		request.breakpointFilePath = fmt.Sprintf("%s.download.bp", request.FilePath)
+	return downloader.run(ctx, []*DownloadRequest{request})[0].Err
+}
+
+// DownloadBatch downloads all requests with a worker pool of Concurrency workers
+// shared by all objects, result of each request is returned in the same order
+func (downloader *Downloader) DownloadBatch(requests []*DownloadRequest) []DownloadResult {
+	return downloader.DownloadBatchWithContext(context.Background(), requests)
+}
+
+// DownloadBatchWithContext downloads all requests with context controlling
+func (downloader *Downloader) DownloadBatchWithContext(ctx context.Context, requests []*DownloadRequest) []DownloadResult {
+	ctx, span := fds.StartSpan(ctx, downloader.client.Configuration.Tracer, "fds.DownloadBatch")
+	defer span.Finish()
+
+	return downloader.run(ctx, requests)
+}
+
+// prepare starts a span for request and makes a downloadJob
+func (downloader *Downloader) prepare(ctx context.Context, request *DownloadRequest) (*downloadJob, error) {
+	ctx, span := fds.StartSpan(ctx, downloader.client.Configuration.Tracer, "fds.Download")
+	span.SetTag(fds.TraceTagBucket, request.BucketName)
+	span.SetTag(fds.TraceTagObject, request.ObjectName)
+
+	job, err := downloader.prepareJob(ctx, request)
+	if err != nil {
+		fds.FinishSpan(span, err)
+		return nil, err
 	}
 
-	var parts []part
-	var err error
+	job.ctx = ctx
+	job.span = span
+	return job, nil
+}
 
+// prepareJob resolves range of request and makes a downloadJob
+func (downloader *Downloader) prepareJob(ctx context.Context, request *DownloadRequest) (*downloadJob, error) {
 	metadata, err := downloader.client.GetObjectMetadataWithContext(ctx, request.BucketName, request.ObjectName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	contentLength, err := strconv.ParseInt(metadata.Get(fds.HTTPHeaderContentMetadataLength), 10, 0)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	ranges, err := httpparser.Range(request.Range)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(ranges) == 0 {
-		ranges = append(ranges, httpparser.HTTPRange{End: contentLength - 1})
+	// the whole object is downloaded if no range is satisfiable
+	ranges, err = httpparser.ResolveRanges(ranges, contentLength)
+	if err != nil {
+		ranges = []httpparser.HTTPRange{{End: contentLength - 1}}
 	}
 
-	if len(ranges) > 1 {
-		return ErrorRnageFormat
+	// r is the span of all ranges with exclusive End
+	r := httpparser.HTTPRange{
+		Start: ranges[0].Start,
+		End:   ranges[len(ranges)-1].End + 1,
+	}
+	var total int64
+	for _, rr := range ranges {
+		total += rr.Length()
 	}
 
-	start := ranges[0].Start
-	end := ranges[0].End + 1
-	if ranges[0].Start < 0 || ranges[0].Start >= contentLength || ranges[0].End > contentLength || ranges[0].Start > ranges[0].End {
-		start = 0
-		end = contentLength
+	listener := request.Progress
+	if listener == nil {
+		listener = downloader.progress
 	}
-	r := httpparser.HTTPRange{
-		Start: start,
-		End:   end,
+	job := &downloadJob{
+		request: request,
+		tracker: fds.NewProgressTracker(listener, request.BucketName, request.ObjectName, total),
 	}
 
-	bp := breakpointInfo{
-		downloader: downloader,
+	parts := downloader.splitRanges(ranges)
+	if request.WriterAt != nil {
+		job.writer = request.WriterAt
+		job.parts = parts
+		return job, nil
 	}
-	if downloader.Breakpoint {
-		// load breakpoint info
-		err = bp.Load(request.breakpointFilePath)
-		if err != nil {
-			bp.Destroy()
-		}
 
-		// validate breakpoint info
-		err = bp.Validate(request.BucketName, request.ObjectName, r)
+	job.tmpFilePath = request.FilePath + ".tmp"
+	if !downloader.Breakpoint {
+		job.parts = parts
+		job.file, err = os.OpenFile(job.tmpFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fds.FilePermMode)
 		if err != nil {
-			downloader.logger.Debug(err)
-			downloader.logger.Debug("breakpoint info is invalid")
-			bp.Initilize(downloader, request.BucketName, request.ObjectName, request.breakpointFilePath, r, metadata)
-			bp.Destroy()
+			return nil, err
 		}
+		job.writer = job.file
+		return job, nil
+	}
 
-		// get parts from breakpoint info
-		parts = bp.UnfinishParts()
+	checkpointFilePath := request.CheckpointFilePath
+	if checkpointFilePath == "" {
+		checkpointFilePath = request.FilePath + CheckpointFileSuffix
+	}
+	cp := newCheckpoint(request, newObjectStat(contentLength, metadata), r, downloader.PartSize)
+	if len(ranges) > 1 {
+		cp.Ranges = httpparser.FormatRange(ranges...)
+	}
+
+	// resume from checkpoint only if it's made for the same download,
+	// and keep parts whose content in temporary file is still correct
+	var records []partRecord
+	saved, savedRecords, err := loadCheckpoint(checkpointFilePath)
+	if err == nil {
+		err = cp.validate(saved)
+	}
+	if err == nil {
+		job.file, err = os.OpenFile(job.tmpFilePath, os.O_RDWR, fds.FilePermMode)
+	}
+	if err == nil {
+		records = verifyParts(job.file, parts, savedRecords)
+		downloader.logger.Debug("resume from checkpoint", fds.Fields{
+			fds.LogFieldOperation: "Download",
+			fds.LogFieldBucket:    request.BucketName,
+			fds.LogFieldObject:    request.ObjectName,
+			"resumed_parts":       len(records),
+			"total_parts":         len(parts),
+		})
 	} else {
-		parts, err = downloader.splitDownloadParts(r)
+		downloader.logger.Debug("breakpoint info is invalid", fds.Fields{
+			fds.LogFieldOperation: "Download",
+			fds.LogFieldBucket:    request.BucketName,
+			fds.LogFieldObject:    request.ObjectName,
+			fds.LogFieldError:     err,
+		})
+		if job.file != nil {
+			job.file.Close()
+		}
+		job.file, err = os.OpenFile(job.tmpFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fds.FilePermMode)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	job.writer = job.file
 
-	jobs := make(chan part, len(parts))
-	results := make(chan part, len(parts))
-	failed := make(chan error)
-	finished := make(chan bool)
+	job.breakpoint, err = createCheckpoint(checkpointFilePath, cp, records)
+	if err != nil {
+		job.file.Close()
+		return nil, err
+	}
 
-	tmpFilePath := request.FilePath + ".tmp"
-	for i := 1; i < downloader.Concurrency; i++ {
-		go downloader.downloaderTaskConsumer(ctx, i, request, tmpFilePath, jobs, results, failed, finished)
+	finished := make([]bool, len(parts))
+	for _, record := range records {
+		finished[record.Index] = true
+		job.written += record.Size
+	}
+	for i, p := range parts {
+		if !finished[i] {
+			job.parts = append(job.parts, p)
+		}
 	}
 
-	go downloader.downloaderTaskProducer(jobs, parts)
+	return job, nil
+}
 
-	completed := 0
-	for completed < len(parts) {
-		select {
-		case p := <-results:
-			completed++
-			if downloader.Breakpoint {
-				bp.PartStat[p.Index] = true
-				bp.Dump()
+// run downloads parts of all requests by Concurrency workers. Requests are prepared
+// at most Concurrency ahead of the workers, and each job is finished as soon as its
+// last part is done, so temporary files are only open for jobs in progress
+func (downloader *Downloader) run(ctx context.Context, requests []*DownloadRequest) []DownloadResult {
+	results := make([]DownloadResult, len(requests))
+	jobs := make([]*downloadJob, len(requests))
+	prepared := make([]chan struct{}, len(requests))
+	for i := range prepared {
+		prepared[i] = make(chan struct{})
+	}
+
+	// metadata of objects is fetched with the same concurrency
+	sem := make(chan struct{}, downloader.Concurrency)
+	go func() {
+		for i, request := range requests {
+			sem <- struct{}{}
+			go func(i int, request *DownloadRequest) {
+				defer close(prepared[i])
+				jobs[i], results[i].Err = downloader.prepare(ctx, request)
+			}(i, request)
+		}
+	}()
+
+	tasks := make(chan partTask)
+	var wg sync.WaitGroup
+	for i := 0; i < downloader.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				downloader.downloadPart(t.job.ctx, t.job, t.part)
+				t.job.release()
 			}
-		case err := <-failed:
-			close(finished)
-			return err
+		}()
+	}
+
+	for i, request := range requests {
+		<-prepared[i]
+		<-sem
+		results[i].Request = request
+		if jobs[i] != nil {
+			downloader.schedule(ctx, jobs[i], tasks)
 		}
 	}
+	close(tasks)
+	wg.Wait()
 
-	if downloader.Breakpoint {
-		os.Remove(request.breakpointFilePath)
+	for i, job := range jobs {
+		if job != nil {
+			results[i].Err = job.result
+			results[i].Size = job.written
+		}
 	}
-	return os.Rename(tmpFilePath, request.FilePath)
+	return results
 }
 
-func (downloader *Downloader) downloaderTaskConsumer(ctx context.Context, id int,
-	request *DownloadRequest, tmpFilePath string, jobs <-chan part, results chan<- part, failed chan<- error, finished <-chan bool) {
-	for p := range jobs {
-		req := &fds.GetObjectRequest{
-			BucketName: request.BucketName,
-			ObjectName: request.ObjectName,
-			Range:      fmt.Sprintf("bytes=%v-%v", p.Start, p.End),
-		}
-		// block in here to take a token from bucket
-		if downloader.limiter != nil {
-			downloader.limiter.Wait(context.Background())
+// schedule sends parts of job to workers, the job is finished by whoever releases it last
+func (downloader *Downloader) schedule(ctx context.Context, job *downloadJob, tasks chan<- partTask) {
+	job.pending = 1
+	defer job.release()
+
+	job.tracker.Started()
+	for _, p := range job.parts {
+		if err := ctx.Err(); err != nil {
+			job.fail(err)
+			return
 		}
-		data, err := downloader.client.GetObjectWithContext(ctx, req)
-		if err != nil {
-			downloader.logger.Debug(err.Error())
-			failed <- err
-			break
+		if job.failed() {
+			return
 		}
-		defer data.Close()
 
+		job.acquire()
 		select {
-		case <-finished:
+		case tasks <- partTask{job: job, part: p}:
+		case <-ctx.Done():
+			job.fail(ctx.Err())
+			job.release()
 			return
-		default:
 		}
+	}
+}
 
-		fd, err := os.OpenFile(tmpFilePath, os.O_WRONLY|os.O_CREATE, os.FileMode(0664))
-		if err != nil {
-			failed <- err
-			break
+func (downloader *Downloader) downloadPart(ctx context.Context, job *downloadJob, p part) {
+	ctx, span := fds.StartSpan(ctx, downloader.client.Configuration.Tracer, "fds.Download.part")
+	span.SetTag(fds.TraceTagPart, p.Index+1)
+	defer span.Finish()
+
+	retryCount := int(downloader.client.Configuration.RetryCount)
+	retryInterval := time.Duration(downloader.client.Configuration.RetryInterval) * time.Millisecond
+	metrics := downloader.client.Configuration.Metrics
+
+	for attempt := 0; ; attempt++ {
+		if job.failed() {
+			return
 		}
 
-		_, err = fd.Seek(p.Start-p.Offset, io.SeekStart)
-		if err != nil {
-			fd.Close()
-			failed <- err
-			break
+		start := time.Now()
+		n, sum, err := downloader.downloadPartOnce(ctx, job, p)
+		if err == nil {
+			metrics.ObservePart("Download", job.request.BucketName, time.Since(start))
+			job.complete(p, n, sum)
+			return
 		}
 
-		_, err = io.Copy(fd, data)
-		if err != nil {
-			fd.Close()
-			failed <- err
-			break
+		span.SetTag(fds.TraceTagAttempt, attempt+1)
+		downloader.logger.Info("download part failed", fds.Fields{
+			fds.LogFieldOperation: "Download",
+			fds.LogFieldBucket:    job.request.BucketName,
+			fds.LogFieldObject:    job.request.ObjectName,
+			fds.LogFieldAttempt:   attempt + 1,
+			fds.LogFieldError:     err,
+			"part":                p.Index + 1,
+		})
+		if attempt >= retryCount || ctx.Err() != nil {
+			span.SetError(err)
+			job.fail(err)
+			return
 		}
 
-		fd.Close()
-		results <- p
+		metrics.ObserveRetry("Download", job.request.BucketName)
+		job.tracker.Retried(p.Index+1, n, err)
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			job.fail(ctx.Err())
+			return
+		}
 	}
 }
 
+// downloadPartOnce downloads part p into writer of job, and returns bytes written with CRC32 of them
+func (downloader *Downloader) downloadPartOnce(ctx context.Context, job *downloadJob, p part) (int64, uint32, error) {
+	// block in here to take a token from bucket
+	if downloader.limiter != nil {
+		if err := downloader.limiter.Wait(ctx); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	req := &fds.GetObjectRequest{
+		BucketName: job.request.BucketName,
+		ObjectName: job.request.ObjectName,
+		Range:      fmt.Sprintf("bytes=%v-%v", p.Start, p.End),
+	}
+	data, err := downloader.client.GetObjectWithContext(ctx, req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer data.Close()
+
+	h := crc32.NewIEEE()
+	w := &offsetWriter{w: job.writer, offset: p.Start - p.Offset, tracker: job.tracker}
+	n, err := io.Copy(io.MultiWriter(w, h), data)
+	if err == nil && n != p.End-p.Start+1 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, h.Sum32(), err
+}
+
+// SetLimiter sets a limiter shared by all workers of downloader
 func (downloader *Downloader) SetLimiter(limiter *rate.Limiter) {
 	downloader.limiter = limiter
 }
 
-func (downloader *Downloader) SetLoggerLevel(level logrus.Level) {
-	downloader.logger.SetLevel(level)
+// SetProgressListener sets a listener receiving progress of every DownloadRequest
+// whose Progress is not set
+func (downloader *Downloader) SetProgressListener(listener fds.ProgressListener) {
+	downloader.progress = listener
 }
 
-func (downloader *Downloader) downloaderTaskProducer(jobs chan part, parts []part) {
-	for _, p := range parts {
-		jobs <- p
-	}
-	close(jobs)
+// SetLogger sets logger of downloader, logger of client is used by default
+func (downloader *Downloader) SetLogger(logger fds.Logger) {
+	downloader.logger = logger
 }
 
-type part struct {
-	Index  int
-	Start  int64
-	End    int64
-	Offset int64
+// SetLoggerLevel makes downloader log into its own logrus logger of level, it replaces
+// the logger set by SetLogger and never changes logger of client.
+//
+// Deprecated: use SetLogger.
+func (downloader *Downloader) SetLoggerLevel(level logrus.Level) {
+	logger := logrus.New()
+	logger.SetLevel(level)
+	downloader.logger = fds.NewLogrusLogger(logger)
 }
 
-func (downloader Downloader) splitDownloadParts(r httpparser.HTTPRange) ([]part, error) {
-	var parts []part
-
-	i := 0
-	for offset := r.Start; offset < r.End; offset += downloader.PartSize {
-		p := part{
-			Index:  i,
-			Start:  offset,
-			End:    getEnd(offset, r.End, downloader.PartSize),
-			Offset: r.Start,
-		}
-		i++
-		parts = append(parts, p)
-	}
+type partTask struct {
+	job  *downloadJob
+	part part
+}
 
-	return parts, nil
+// downloadJob is the state of one DownloadRequest
+type downloadJob struct {
+	mu sync.Mutex
+
+	request     *DownloadRequest
+	parts       []part
+	writer      io.WriterAt
+	file        *os.File
+	tmpFilePath string
+	breakpoint  *checkpointJournal
+	tracker     *fds.ProgressTracker
+	ctx         context.Context
+	span        fds.Span
+
+	pending int
+	written int64
+	err     error
+	result  error
 }
 
-func getEnd(begin int64, total int64, per int64) int64 {
-	if begin+per > total {
-		return total - 1
+// complete records part p, and fails job if checkpoint can't record it
+func (job *downloadJob) complete(p part, n int64, sum uint32) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.written += n
+	job.tracker.PartCompleted(p.Index+1, n)
+	if job.breakpoint != nil {
+		err := job.breakpoint.Append(partRecord{Index: p.Index, Size: n, CRC32: sum})
+		if err != nil && job.err == nil {
+			job.err = err
+		}
 	}
-	return begin + per - 1
 }
 
-type breakpointInfo struct {
-	FilePath   string
-	BucketName string
-	ObjectName string
-	ObjectStat objectStat
-	Parts      []part
-	PartStat   []bool
-	Start      int64
-	End        int64
-	MD5        string
+func (job *downloadJob) fail(err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
 
-	downloader *Downloader
+	if job.err == nil {
+		job.err = err
+	}
 }
 
-type objectStat struct {
-	Size         int64  // Object size
-	LastModified string // Last modified time
+func (job *downloadJob) failed() bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	return job.err != nil
 }
 
-func (bp *breakpointInfo) Load(path string) error {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
+func (job *downloadJob) acquire() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
 
-	return json.Unmarshal(data, bp)
+	job.pending++
 }
 
-func (bp *breakpointInfo) Dump() error {
-	bpi := *bp
+// release drops a reference to job held by a scheduled part, and finishes job by the last one
+func (job *downloadJob) release() {
+	job.mu.Lock()
+	job.pending--
+	last := job.pending == 0
+	job.mu.Unlock()
 
-	bpi.MD5 = ""
-	data, err := json.Marshal(bpi)
-	if err != nil {
-		return err
+	if last {
+		job.result = job.finish()
 	}
+}
 
-	sum := md5.Sum(data)
-	b64 := base64.StdEncoding.EncodeToString(sum[:])
-	bpi.MD5 = b64
-
-	data, err = json.Marshal(bpi)
+// finish closes the temporary file and moves it to FilePath if all parts are downloaded
+func (job *downloadJob) finish() error {
+	err := job.close()
+	fds.FinishSpan(job.span, err)
 	if err != nil {
-		return err
+		job.tracker.Failed(err)
+	} else {
+		job.tracker.Completed()
 	}
-
-	return ioutil.WriteFile(bpi.FilePath, data, os.FileMode(0664))
+	return err
 }
 
-func (bp *breakpointInfo) Validate(bucketName, objectName string, r httpparser.HTTPRange) error {
-	if bucketName != bp.BucketName || objectName != bp.ObjectName {
-		return ErrorBucketOrObjectNotMatching
+func (job *downloadJob) close() error {
+	if job.file == nil {
+		return job.err
 	}
 
-	bpi := *bp
-	bpi.MD5 = ""
-	data, err := json.Marshal(bpi)
-	if err != nil {
-		return err
+	err := job.file.Close()
+	if job.breakpoint != nil {
+		job.breakpoint.Close(job.err == nil && err == nil)
 	}
-	sum := md5.Sum(data)
-	b64 := base64.StdEncoding.EncodeToString(sum[:])
-	if b64 != bp.MD5 {
-		return ErrorMD5NotMatching
+	if job.err != nil {
+		return job.err
 	}
-
-	c := bp.downloader.client
-	metadata, err := c.GetObjectMetadata(bucketName, objectName)
 	if err != nil {
 		return err
 	}
 
-	length, err := metadata.GetContentLength()
-	if err != nil {
-		return err
-	}
-	if bp.ObjectStat.Size != length || bp.ObjectStat.LastModified != metadata.Get(fds.HTTPHeaderLastModified) {
-		return ErrorObjectStateNotMatching
-	}
+	return os.Rename(job.tmpFilePath, job.request.FilePath)
+}
 
-	if bp.Start != r.Start || bp.End != r.End {
-		return ErrorRangeNotMatching
-	}
+// offsetWriter turns io.WriterAt into io.Writer from offset
+type offsetWriter struct {
+	w       io.WriterAt
+	offset  int64
+	tracker *fds.ProgressTracker
+}
 
-	return nil
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.offset)
+	ow.offset += int64(n)
+	ow.tracker.Transferred(int64(n))
+	return n, err
 }
 
-func (bp *breakpointInfo) UnfinishParts() []part {
-	var result []part
+// WriteAtBuffer is an in-memory io.WriterAt, it grows when needed
+type WriteAtBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
 
-	for i, s := range bp.PartStat {
-		if !s {
-			result = append(result, bp.Parts[i])
-		}
+// NewWriteAtBuffer creates a WriteAtBuffer with buf as its initial content,
+// pass a buffer with enough capacity to avoid growing
+func NewWriteAtBuffer(buf []byte) *WriteAtBuffer {
+	return &WriteAtBuffer{buf: buf}
+}
+
+// WriteAt writes p into buffer at offset off
+func (b *WriteAtBuffer) WriteAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	end := int(off) + len(p)
+	if end > cap(b.buf) {
+		buf := make([]byte, end, end*2)
+		copy(buf, b.buf)
+		b.buf = buf
+	} else if end > len(b.buf) {
+		b.buf = b.buf[:end]
 	}
+	copy(b.buf[off:], p)
 
-	return result
+	return len(p), nil
 }
 
-func (bp *breakpointInfo) Initilize(downloader *Downloader,
-	bucketName, objectName, filePath string, r httpparser.HTTPRange, md *fds.ObjectMetadata) error {
-	bp.MD5 = ""
-	bp.BucketName = bucketName
-	bp.ObjectName = objectName
-	bp.FilePath = filePath
-	bp.Start = r.Start
-	bp.End = r.End
-	bp.downloader = downloader
+// Bytes returns content of buffer
+func (b *WriteAtBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	contentLength, err := md.GetContentLength()
-	if err != nil {
-		return err
-	}
+	return b.buf
+}
 
-	parts, err := downloader.splitDownloadParts(r)
-	if err != nil {
-		return err
+type part struct {
+	Index  int
+	Start  int64
+	End    int64
+	Offset int64
+}
+
+func (downloader Downloader) splitDownloadParts(r httpparser.HTTPRange) ([]part, error) {
+	var parts []part
+
+	i := 0
+	for offset := r.Start; offset < r.End; offset += downloader.PartSize {
+		p := part{
+			Index:  i,
+			Start:  offset,
+			End:    getEnd(offset, r.End, downloader.PartSize),
+			Offset: r.Start,
+		}
+		i++
+		parts = append(parts, p)
 	}
-	bp.Parts = parts
 
-	bp.PartStat = make([]bool, len(bp.Parts))
+	return parts, nil
+}
 
-	bp.ObjectStat = objectStat{
-		Size:         contentLength,
-		LastModified: md.Get(fds.HTTPHeaderLastModified),
+// splitRanges splits resolved ranges into parts, content of a single range is
+// written from offset 0, while multiple ranges are written at their offsets in object
+func (downloader Downloader) splitRanges(ranges []httpparser.HTTPRange) []part {
+	if len(ranges) == 1 {
+		parts, _ := downloader.splitDownloadParts(httpparser.HTTPRange{Start: ranges[0].Start, End: ranges[0].End + 1})
+		return parts
 	}
 
-	return nil
+	var parts []part
+	for _, r := range ranges {
+		rangeParts, _ := downloader.splitDownloadParts(httpparser.HTTPRange{Start: r.Start, End: r.End + 1})
+		for _, p := range rangeParts {
+			p.Index = len(parts)
+			p.Offset = 0
+			parts = append(parts, p)
+		}
+	}
+	return parts
 }
 
-func (bp *breakpointInfo) Destroy() {
-	os.Remove(bp.FilePath)
+func getEnd(begin int64, total int64, per int64) int64 {
+	if begin+per > total {
+		return total - 1
+	}
+	return begin + per - 1
 }
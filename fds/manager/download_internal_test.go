@@ -1,7 +1,302 @@
 package manager
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestDownloader_splitDownloadParts(t *testing.T) {
+	downloader := &Downloader{PartSize: 4}
+
+	parts, err := downloader.splitDownloadParts(httpparser.HTTPRange{Start: 2, End: 12})
+	assert.Nil(t, err)
+	assert.Equal(t, []part{
+		{Index: 0, Start: 2, End: 5, Offset: 2},
+		{Index: 1, Start: 6, End: 9, Offset: 2},
+		{Index: 2, Start: 10, End: 11, Offset: 2},
+	}, parts)
+}
+
+func TestDownloader_SetLoggerLevel(t *testing.T) {
+	client := newFakeServer(t).Client()
+	downloader, err := NewDownloader(client, 7, 3, false)
+	assert.Nil(t, err)
+	assert.True(t, client.Logger() == downloader.logger)
+
+	// logger of client is shared with other users, so it's replaced instead of changed
+	downloader.SetLoggerLevel(logrus.DebugLevel)
+	assert.False(t, client.Logger() == downloader.logger)
+}
+
+func TestDownloader_DownloadWriterAt(t *testing.T) {
+	server := newFakeServer(t)
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	server.PutObject("bucket", "object", content)
+
+	downloader, err := NewDownloader(server.Client(), 7, 3, false)
+	assert.Nil(t, err)
+
+	buf := NewWriteAtBuffer(nil)
+	err = downloader.Download(&DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+		},
+		WriterAt: buf,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, content, buf.Bytes())
+
+	buf = NewWriteAtBuffer(nil)
+	err = downloader.Download(&DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+			Range:      "bytes=15-39",
+		},
+		WriterAt: buf,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, content[15:40], buf.Bytes())
+}
+
+func TestDownloader_DownloadSparseRanges(t *testing.T) {
+	server := newFakeServer(t)
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	server.PutObject("bucket", "object", content)
+
+	downloader, err := NewDownloader(server.Client(), 7, 3, true)
+	assert.Nil(t, err)
+
+	filePath := filepath.Join(t.TempDir(), "object")
+	err = downloader.Download(&DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+			Range:      "bytes=5-19,50-,-5,200-300",
+		},
+		FilePath: filePath,
+	})
+	assert.Nil(t, err)
+
+	// ranges are at their offsets of object, and the unsatisfiable one is ignored
+	data, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, len(content), len(data))
+	assert.Equal(t, make([]byte, 5), data[:5])
+	assert.Equal(t, content[5:20], data[5:20])
+	assert.Equal(t, make([]byte, 30), data[20:50])
+	assert.Equal(t, content[50:], data[50:])
+}
+
+func TestDownloader_DownloadBatch(t *testing.T) {
+	server := newFakeServer(t)
+	dir := t.TempDir()
+
+	var requests []*DownloadRequest
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("shard-%d", i)
+		server.PutObject("bucket", name, bytes.Repeat([]byte{byte('a' + i)}, 10*i+3))
+		requests = append(requests, &DownloadRequest{
+			GetObjectRequest: fds.GetObjectRequest{
+				BucketName: "bucket",
+				ObjectName: name,
+			},
+			FilePath: filepath.Join(dir, name),
+		})
+	}
+	requests = append(requests, &DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "missing",
+		},
+		WriterAt: NewWriteAtBuffer(nil),
+	})
+
+	downloader, err := NewDownloader(server.Client(), 4, 4, false)
+	assert.Nil(t, err)
+
+	results := downloader.DownloadBatch(requests)
+	assert.Len(t, results, len(requests))
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, results[i].Err)
+		assert.Equal(t, int64(10*i+3), results[i].Size)
+
+		data, err := ioutil.ReadFile(requests[i].FilePath)
+		assert.Nil(t, err)
+		assert.Equal(t, bytes.Repeat([]byte{byte('a' + i)}, 10*i+3), data)
+	}
+	assert.NotNil(t, results[5].Err)
+	assert.Equal(t, requests[5], results[5].Request)
+}
+
+func TestDownloader_DownloadBatchOpenFiles(t *testing.T) {
+	server := newFakeServer(t)
+	dir := t.TempDir()
+
+	var requests []*DownloadRequest
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("shard-%d", i)
+		server.PutObject("bucket", name, bytes.Repeat([]byte{byte('a' + i)}, 10))
+		requests = append(requests, &DownloadRequest{
+			GetObjectRequest: fds.GetObjectRequest{
+				BucketName: "bucket",
+				ObjectName: name,
+			},
+			FilePath: filepath.Join(dir, name),
+		})
+	}
+
+	// temporary files only exist for jobs being downloaded or prepared ahead
+	var mu sync.Mutex
+	var maxOpen int
+	server.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+			mu.Lock()
+			defer mu.Unlock()
+			tmps, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+			if len(tmps) > maxOpen {
+				maxOpen = len(tmps)
+			}
+		}
+		return true
+	}
+
+	downloader, err := NewDownloader(server.Client(), 4, 2, false)
+	assert.Nil(t, err)
+
+	results := downloader.DownloadBatch(requests)
+	for i := range requests {
+		assert.Nil(t, results[i].Err)
+		assert.Equal(t, int64(10), results[i].Size)
+	}
+	assert.True(t, maxOpen > 0)
+	assert.True(t, maxOpen <= 5, maxOpen)
+}
+
+func TestDownloader_DownloadProgress(t *testing.T) {
+	server := newFakeServer(t)
+	content := bytes.Repeat([]byte("abcdefgh"), 8)
+	server.PutObject("bucket", "object", content)
+
+	var once sync.Once
+	server.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		failed := false
+		if r.Header.Get(fds.HTTPHeaderRange) != "" {
+			once.Do(func() {
+				w.WriteHeader(http.StatusInternalServerError)
+				failed = true
+			})
+		}
+		return !failed
+	}
+
+	client := server.Client()
+	client.Configuration.RetryInterval = 1
+	downloader, err := NewDownloader(client, 16, 2, false)
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	counts := map[fds.ProgressEventType]int{}
+	var last fds.ProgressEvent
+	downloader.SetProgressListener(fds.ProgressListenerFunc(func(event *fds.ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[event.Type]++
+		last = *event
+	}))
+
+	err = downloader.Download(&DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+		},
+		WriterAt: NewWriteAtBuffer(nil),
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, counts[fds.ProgressStarted])
+	assert.Equal(t, 1, counts[fds.ProgressRetried])
+	assert.Equal(t, 4, counts[fds.ProgressPartCompleted])
+	assert.Equal(t, 1, counts[fds.ProgressCompleted])
+	assert.Equal(t, fds.ProgressCompleted, last.Type)
+	assert.Equal(t, int64(len(content)), last.ConsumedBytes)
+	assert.Equal(t, int64(len(content)), last.TotalBytes)
+}
+
+type testSpan struct {
+	name   string
+	parent *testSpan
+}
+
+func (s *testSpan) SetTag(key string, value interface{}) {}
+func (s *testSpan) SetError(err error)                   {}
+func (s *testSpan) Finish()                              {}
+
+type testSpanKey struct{}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) StartSpan(ctx context.Context, operation string) (context.Context, fds.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &testSpan{name: operation}
+	span.parent, _ = ctx.Value(testSpanKey{}).(*testSpan)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (t *testTracer) Inject(ctx context.Context, header http.Header) {}
+
+func TestDownloader_DownloadTracing(t *testing.T) {
+	server := newFakeServer(t)
+	server.PutObject("bucket", "object", bytes.Repeat([]byte("x"), 30))
+
+	tracer := &testTracer{}
+	client := server.Client()
+	client.Configuration.Tracer = tracer
+	downloader, err := NewDownloader(client, 10, 2, false)
+	assert.Nil(t, err)
+
+	err = downloader.Download(&DownloadRequest{
+		GetObjectRequest: fds.GetObjectRequest{
+			BucketName: "bucket",
+			ObjectName: "object",
+		},
+		WriterAt: NewWriteAtBuffer(nil),
+	})
+	assert.Nil(t, err)
 
+	names := map[string]int{}
+	for _, span := range tracer.spans {
+		names[span.name]++
+		switch span.name {
+		case "fds.Download":
+			assert.Nil(t, span.parent)
+		case "fds.Download.part":
+			assert.Equal(t, "fds.Download", span.parent.name)
+		case "fds.GetObject":
+			assert.Equal(t, "fds.Download.part", span.parent.name)
+		case "fds.GetObjectMetadata":
+			assert.Equal(t, "fds.Download", span.parent.name)
+		}
+	}
+	assert.Equal(t, 1, names["fds.Download"])
+	assert.Equal(t, 3, names["fds.Download.part"])
+	assert.Equal(t, 3, names["fds.GetObject"])
 }
@@ -13,4 +13,5 @@ var (
 	ErrorRangeNotMatching          = errors.New("Range is not matching")
 	ErrorFileNotFound              = errors.New("File is not found")
 	ErrorTooManyUploadParts        = errors.New("Too many upload parts, increase PartSize please")
+	ErrorReplicaNotMatching        = errors.New("Replica is not matching its source")
 )
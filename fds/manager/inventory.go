@@ -0,0 +1,554 @@
+package manager
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+)
+
+// InventoryFormat is format of inventory file
+type InventoryFormat string
+
+// InventoryFormat const
+const (
+	InventoryCSV       InventoryFormat = "csv"
+	InventoryJSONLines InventoryFormat = "jsonl"
+)
+
+// Default boundaries of InventoryReport.BySize and InventoryReport.ByAge
+var (
+	DefaultSizeBuckets = []int64{1 << 10, 1 << 20, 16 << 20, 128 << 20, 1 << 30}
+	DefaultAgeBuckets  = []time.Duration{24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour,
+		90 * 24 * time.Hour, 365 * 24 * time.Hour}
+)
+
+// InventoryItem is a line of inventory file
+type InventoryItem struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"lastModified"`
+	Owner        string    `json:"owner"`
+}
+
+var inventoryCSVHeader = []string{"key", "size", "etag", "last_modified", "owner"}
+
+// InventoryStats counts objects and sums their size
+type InventoryStats struct {
+	Objects int64 `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+func (stats *InventoryStats) add(size int64) {
+	stats.Objects++
+	stats.Size += size
+}
+
+// InventoryReport aggregates objects of a bucket. Keys of ByPrefix are the first PrefixDepth
+// segments of object names, e.g. "a/b/" for a/b/c/d with depth 2. Keys of BySize and ByAge
+// are ranges of buckets, e.g. "1KiB-1MiB" and "7d-30d".
+type InventoryReport struct {
+	BucketName  string                     `json:"bucketName"`
+	Prefix      string                     `json:"prefix"`
+	GeneratedAt time.Time                  `json:"generatedAt"`
+	Total       InventoryStats             `json:"total"`
+	ByPrefix    map[string]*InventoryStats `json:"byPrefix"`
+	BySize      map[string]*InventoryStats `json:"bySize"`
+	ByAge       map[string]*InventoryStats `json:"byAge"`
+}
+
+// WriteJSON writes report as indented JSON
+func (report *InventoryReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// Inventory lists all objects of a bucket concurrently, the keyspace is sharded by
+// prefixes of the first level
+type Inventory struct {
+	client  *fds.Client
+	limiter *rate.Limiter
+
+	Concurrency int
+}
+
+// NewInventory new an Inventory
+func NewInventory(client *fds.Client, concurrency int) (*Inventory, error) {
+	if concurrency < 1 {
+		return nil, ErrorConcurrencySmallerThanOne
+	}
+
+	return &Inventory{
+		Concurrency: concurrency,
+
+		client: client,
+	}, nil
+}
+
+// SetLimiter sets a limiter shared by all workers, each listing request takes a token
+func (inventory *Inventory) SetLimiter(limiter *rate.Limiter) {
+	inventory.limiter = limiter
+}
+
+// InventoryRequest is input of Run
+type InventoryRequest struct {
+	BucketName string
+	Prefix     string
+
+	// Output receives inventory items in Format, CSV is the default format.
+	// Only the report is made if it's nil.
+	Output io.Writer
+	Format InventoryFormat
+
+	// PrefixDepth is how many segments split by "/" are kept as key of ByPrefix, 1 if it's 0
+	PrefixDepth int
+	// SizeBuckets and AgeBuckets are ascending boundaries, defaults are used if they're empty
+	SizeBuckets []int64
+	AgeBuckets  []time.Duration
+	// Now is when ages are measured from, current time is used if it's zero
+	Now time.Time
+
+	// CheckpointFilePath saves progress of each shard and size of Output if it's set. If the
+	// file exists, run resumes from it and Output should be the same file opened for appending.
+	// Output is truncated to the saved size if it has Truncate and Seek like *os.File, otherwise
+	// items written after the last save are written again. The file is removed after run finishes.
+	CheckpointFilePath string
+}
+
+// inventoryShard is a listing of prefix. The root shard is listed with Delimiter first, and
+// each common prefix found in it becomes another shard.
+type inventoryShard struct {
+	Prefix    string `json:"prefix"`
+	Delimiter string `json:"delimiter,omitempty"`
+	Marker    string `json:"marker,omitempty"`
+	Done      bool   `json:"done,omitempty"`
+}
+
+// inventoryCheckpoint is rewritten after each batch of listing
+type inventoryCheckpoint struct {
+	Version     int              `json:"version"`
+	BucketName  string           `json:"bucketName"`
+	Prefix      string           `json:"prefix"`
+	Format      InventoryFormat  `json:"format"`
+	PrefixDepth int              `json:"prefixDepth"`
+	Offset      int64            `json:"offset"`
+	Shards      []inventoryShard `json:"shards"`
+	Report      *InventoryReport `json:"report"`
+}
+
+// inventoryJob is state of a run shared by workers
+type inventoryJob struct {
+	mu      sync.Mutex
+	request *InventoryRequest
+	cp      *inventoryCheckpoint
+	output  *countingWriter
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+// countingWriter counts offset of Output, which is saved in checkpoint
+type countingWriter struct {
+	w      io.Writer
+	offset int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.offset += int64(n)
+	return n, err
+}
+
+// truncater is an Output which can be cut back to the size saved in checkpoint
+type truncater interface {
+	io.Seeker
+	Truncate(size int64) error
+}
+
+// Run lists all objects of bucket with prefix, writes them into Output and returns the report
+func (inventory *Inventory) Run(request *InventoryRequest) (*InventoryReport, error) {
+	return inventory.RunWithContext(context.Background(), request)
+}
+
+// RunWithContext lists all objects of bucket with prefix with context controlling
+func (inventory *Inventory) RunWithContext(ctx context.Context, request *InventoryRequest) (report *InventoryReport, err error) {
+	ctx, span := fds.StartSpan(ctx, inventory.client.Configuration.Tracer, "fds.Inventory")
+	span.SetTag(fds.TraceTagBucket, request.BucketName)
+	defer func() {
+		fds.FinishSpan(span, err)
+	}()
+
+	job, err := inventory.newJob(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// shards are found by the root shard, so it's walked before the others
+	if !job.cp.Shards[0].Done {
+		if err := inventory.walkShard(ctx, job, 0); err != nil {
+			return job.cp.Report, err
+		}
+	}
+
+	shards := make(chan int)
+	group, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < inventory.Concurrency; i++ {
+		group.Go(func() error {
+			for index := range shards {
+				if err := inventory.walkShard(ctx, job, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	group.Go(func() error {
+		defer close(shards)
+		for i, shard := range job.cp.Shards {
+			if shard.Done {
+				continue
+			}
+			select {
+			case shards <- i:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	if err := group.Wait(); err != nil {
+		return job.cp.Report, err
+	}
+
+	if request.CheckpointFilePath != "" {
+		os.Remove(request.CheckpointFilePath)
+	}
+	return job.cp.Report, nil
+}
+
+// newJob resumes from checkpoint if it exists, or starts with the root shard
+func (inventory *Inventory) newJob(request *InventoryRequest) (*inventoryJob, error) {
+	job := &inventoryJob{request: request}
+	format := request.Format
+	if format == "" {
+		format = InventoryCSV
+	}
+	if format != InventoryCSV && format != InventoryJSONLines {
+		return nil, fmt.Errorf("unknown inventory format %q", format)
+	}
+
+	depth := request.PrefixDepth
+	if depth <= 0 {
+		depth = 1
+	}
+	cp := &inventoryCheckpoint{
+		Version:     checkpointVersion,
+		BucketName:  request.BucketName,
+		Prefix:      request.Prefix,
+		Format:      format,
+		PrefixDepth: depth,
+	}
+
+	if request.CheckpointFilePath != "" {
+		data, err := ioutil.ReadFile(request.CheckpointFilePath)
+		if err == nil {
+			saved := &inventoryCheckpoint{}
+			if err := json.Unmarshal(data, saved); err != nil {
+				return nil, err
+			}
+			if saved.Version != cp.Version || saved.BucketName != cp.BucketName || saved.Prefix != cp.Prefix ||
+				saved.Format != cp.Format || saved.PrefixDepth != cp.PrefixDepth || saved.Report == nil ||
+				len(saved.Shards) == 0 {
+				return nil, ErrorBucketOrObjectNotMatching
+			}
+			job.cp = saved
+			return job, job.resumeOutput()
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	now := request.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cp.Report = &InventoryReport{
+		BucketName:  request.BucketName,
+		Prefix:      request.Prefix,
+		GeneratedAt: now,
+		ByPrefix:    map[string]*InventoryStats{},
+		BySize:      map[string]*InventoryStats{},
+		ByAge:       map[string]*InventoryStats{},
+	}
+
+	cp.Shards = []inventoryShard{{Prefix: request.Prefix, Delimiter: "/"}}
+	job.cp = cp
+
+	// items are appended to Output, the header of CSV is written before the first save
+	if request.Output != nil {
+		if seeker, ok := request.Output.(io.Seeker); ok {
+			offset, err := seeker.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, err
+			}
+			cp.Offset = offset
+		}
+	}
+	job.newWriter(format)
+	if job.csv != nil {
+		job.csv.Write(inventoryCSVHeader)
+		if err := job.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return job, job.save()
+}
+
+// resumeOutput cuts Output back to the offset saved in checkpoint if it can
+func (job *inventoryJob) resumeOutput() error {
+	if output, ok := job.request.Output.(truncater); ok {
+		if err := output.Truncate(job.cp.Offset); err != nil {
+			return err
+		}
+		if _, err := output.Seek(job.cp.Offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	job.newWriter(job.cp.Format)
+	return nil
+}
+
+func (job *inventoryJob) newWriter(format InventoryFormat) {
+	if job.request.Output == nil {
+		return
+	}
+	job.output = &countingWriter{w: job.request.Output, offset: job.cp.Offset}
+	switch format {
+	case InventoryCSV:
+		job.csv = csv.NewWriter(job.output)
+	case InventoryJSONLines:
+		job.json = json.NewEncoder(job.output)
+	}
+}
+
+// flush flushes CSV writer and updates offset of Output in checkpoint
+func (job *inventoryJob) flush() error {
+	if job.csv != nil {
+		job.csv.Flush()
+		if err := job.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if job.output != nil {
+		job.cp.Offset = job.output.offset
+	}
+	return nil
+}
+
+// list lists the first batch of shard, or the batch after its marker
+func (inventory *Inventory) list(ctx context.Context, bucketName string, shard *inventoryShard) (*fds.ObjectListing, error) {
+	if shard.Marker != "" {
+		return inventory.next(ctx, &fds.ObjectListing{
+			BucketName: bucketName,
+			Prefix:     shard.Prefix,
+			Delimiter:  shard.Delimiter,
+			MaxKeys:    fds.DefaultListObjectsMaxKeys,
+			NextMarker: shard.Marker,
+		})
+	}
+
+	if inventory.limiter != nil {
+		if err := inventory.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return inventory.client.ListObjectsWithContext(ctx, &fds.ListObjectsRequest{
+		BucketName: bucketName,
+		Prefix:     shard.Prefix,
+		Delimiter:  shard.Delimiter,
+		MaxKeys:    fds.DefaultListObjectsMaxKeys,
+	})
+}
+
+func (inventory *Inventory) next(ctx context.Context, previous *fds.ObjectListing) (*fds.ObjectListing, error) {
+	if inventory.limiter != nil {
+		if err := inventory.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return inventory.client.ListObjectsNextBatchWithContext(ctx, previous)
+}
+
+// walkShard lists shard from its marker to the end
+func (inventory *Inventory) walkShard(ctx context.Context, job *inventoryJob, index int) error {
+	job.mu.Lock()
+	shard := job.cp.Shards[index]
+	job.mu.Unlock()
+
+	listing, err := inventory.list(ctx, job.cp.BucketName, &shard)
+	for {
+		if err != nil {
+			return err
+		}
+		if err := job.commit(index, listing); err != nil {
+			return err
+		}
+		if !listing.Truncated {
+			return nil
+		}
+		listing, err = inventory.next(ctx, listing)
+	}
+}
+
+// commit writes a batch of shard, adds it into report and saves checkpoint,
+// common prefixes of the root shard are added as shards
+func (job *inventoryJob) commit(index int, listing *fds.ObjectListing) error {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	for i := range listing.ObjectSummaries {
+		object := &listing.ObjectSummaries[i]
+		if err := job.write(object); err != nil {
+			return err
+		}
+		job.add(object)
+	}
+	if err := job.flush(); err != nil {
+		return err
+	}
+	for _, prefix := range listing.CommonPrefixes {
+		job.cp.Shards = append(job.cp.Shards, inventoryShard{Prefix: prefix})
+	}
+
+	shard := &job.cp.Shards[index]
+	shard.Marker = listing.NextMarker
+	shard.Done = !listing.Truncated
+	return job.save()
+}
+
+func (job *inventoryJob) write(object *fds.ObjectSummary) error {
+	item := InventoryItem{
+		Key:          object.ObjectName,
+		Size:         object.Size,
+		ETag:         object.ETag,
+		LastModified: object.LastModified,
+		Owner:        object.Owner.ID,
+	}
+	switch {
+	case job.csv != nil:
+		return job.csv.Write([]string{item.Key, strconv.FormatInt(item.Size, 10), item.ETag,
+			item.LastModified.UTC().Format(time.RFC3339), item.Owner})
+	case job.json != nil:
+		return job.json.Encode(item)
+	}
+	return nil
+}
+
+func (job *inventoryJob) add(object *fds.ObjectSummary) {
+	report := job.cp.Report
+	report.Total.add(object.Size)
+
+	sizeBuckets := job.request.SizeBuckets
+	if len(sizeBuckets) == 0 {
+		sizeBuckets = DefaultSizeBuckets
+	}
+	ageBuckets := job.request.AgeBuckets
+	if len(ageBuckets) == 0 {
+		ageBuckets = DefaultAgeBuckets
+	}
+
+	addStats(report.ByPrefix, prefixKey(object.ObjectName, job.cp.PrefixDepth), object.Size)
+	addStats(report.BySize, sizeLabel(object.Size, sizeBuckets), object.Size)
+	addStats(report.ByAge, ageLabel(report.GeneratedAt.Sub(object.LastModified), ageBuckets), object.Size)
+}
+
+// save rewrites checkpoint file atomically
+func (job *inventoryJob) save() error {
+	path := job.request.CheckpointFilePath
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(job.cp)
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func addStats(m map[string]*InventoryStats, key string, size int64) {
+	stats, ok := m[key]
+	if !ok {
+		stats = &InventoryStats{}
+		m[key] = stats
+	}
+	stats.add(size)
+}
+
+// prefixKey returns the first depth segments of name, or its directory if it's not deep enough
+func prefixKey(name string, depth int) string {
+	segments := strings.Split(name, "/")
+	if len(segments) > depth {
+		return strings.Join(segments[:depth], "/") + "/"
+	}
+	return name[:strings.LastIndex(name, "/")+1]
+}
+
+func sizeLabel(size int64, buckets []int64) string {
+	for i, b := range buckets {
+		if size < b {
+			if i == 0 {
+				return "<" + formatSize(b)
+			}
+			return formatSize(buckets[i-1]) + "-" + formatSize(b)
+		}
+	}
+	return ">=" + formatSize(buckets[len(buckets)-1])
+}
+
+func ageLabel(age time.Duration, buckets []time.Duration) string {
+	for i, b := range buckets {
+		if age < b {
+			if i == 0 {
+				return "<" + formatAge(b)
+			}
+			return formatAge(buckets[i-1]) + "-" + formatAge(b)
+		}
+	}
+	return ">=" + formatAge(buckets[len(buckets)-1])
+}
+
+// formatSize formats size in the largest binary unit dividing it
+func formatSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for i < len(units)-1 && size != 0 && size%1024 == 0 {
+		size /= 1024
+		i++
+	}
+	return strconv.FormatInt(size, 10) + units[i]
+}
+
+// formatAge formats whole days as "7d", others by time.Duration
+func formatAge(d time.Duration) string {
+	day := 24 * time.Hour
+	if d != 0 && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
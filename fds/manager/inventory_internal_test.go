@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newInventoryServer(t *testing.T, now time.Time) *fakeServer {
+	server := newFakeServer(t)
+	server.pageSize = 1
+	objects := map[string]struct {
+		size int
+		age  time.Duration
+	}{
+		"top":     {10, time.Hour},
+		"a/1":     {2048, 3 * 24 * time.Hour},
+		"a/2":     {100, 40 * 24 * time.Hour},
+		"a/b/3":   {1 << 20, 400 * 24 * time.Hour},
+		"b/1":     {0, time.Hour},
+		"b/c/d/4": {5, time.Hour},
+	}
+	for name, o := range objects {
+		server.objects["bucket/"+name] = &fakeObject{
+			data:         make([]byte, o.size),
+			lastModified: now.Add(-o.age),
+			etag:         "etag-" + name,
+		}
+	}
+	server.objects["other/a/1"] = &fakeObject{}
+	return server
+}
+
+func TestInventory_Run(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	server := newInventoryServer(t, now)
+	var rootLists int32
+	server.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if query := r.URL.Query(); query.Get("delimiter") == "/" && query.Get("marker") == "" {
+			atomic.AddInt32(&rootLists, 1)
+		}
+		return true
+	}
+
+	_, err := NewInventory(server.Client(), 0)
+	assert.Equal(t, ErrorConcurrencySmallerThanOne, err)
+	inventory, _ := NewInventory(server.Client(), 3)
+
+	var out bytes.Buffer
+	report, err := inventory.Run(&InventoryRequest{
+		BucketName:  "bucket",
+		Output:      &out,
+		Format:      InventoryJSONLines,
+		PrefixDepth: 2,
+		Now:         now,
+	})
+	assert.Nil(t, err)
+
+	var keys []string
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var item InventoryItem
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &item))
+		assert.Equal(t, "etag-"+item.Key, item.ETag)
+		keys = append(keys, item.Key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a/1", "a/2", "a/b/3", "b/1", "b/c/d/4", "top"}, keys)
+	assert.Equal(t, int32(1), rootLists)
+
+	assert.Equal(t, InventoryStats{Objects: 6, Size: 2048 + 100 + 1<<20 + 15}, report.Total)
+	assert.Equal(t, InventoryStats{Objects: 1, Size: 10}, *report.ByPrefix[""])
+	assert.Equal(t, InventoryStats{Objects: 2, Size: 2148}, *report.ByPrefix["a/"])
+	assert.Equal(t, InventoryStats{Objects: 1, Size: 1 << 20}, *report.ByPrefix["a/b/"])
+	assert.Equal(t, InventoryStats{Objects: 1, Size: 5}, *report.ByPrefix["b/c/"])
+	assert.Equal(t, int64(4), report.BySize["<1KiB"].Objects)
+	assert.Equal(t, int64(1), report.BySize["1KiB-1MiB"].Objects)
+	assert.Equal(t, int64(1), report.BySize["1MiB-16MiB"].Objects)
+	assert.Equal(t, int64(3), report.ByAge["<1d"].Objects)
+	assert.Equal(t, int64(1), report.ByAge["1d-7d"].Objects)
+	assert.Equal(t, int64(1), report.ByAge["30d-90d"].Objects)
+	assert.Equal(t, int64(1), report.ByAge[">=365d"].Objects)
+}
+
+func TestInventory_Resume(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	server := newInventoryServer(t, now)
+	var lists int32
+	server.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.AddInt32(&lists, 1) > 6 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return false
+		}
+		return true
+	}
+
+	inventory, _ := NewInventory(server.Client(), 2)
+	request := &InventoryRequest{
+		BucketName:         "bucket",
+		Now:                now,
+		CheckpointFilePath: filepath.Join(t.TempDir(), "inventory.cp"),
+	}
+	outputPath := filepath.Join(t.TempDir(), "inventory.csv")
+	open := func() *os.File {
+		f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		assert.Nil(t, err)
+		return f
+	}
+	output := open()
+	request.Output = output
+	_, err := inventory.Run(request)
+	assert.NotNil(t, err)
+	assert.FileExists(t, request.CheckpointFilePath)
+
+	// items written after the last save are cut when resuming
+	output.WriteString("written,after,save,,\n")
+	output.Close()
+
+	request.Format = InventoryJSONLines
+	_, err = inventory.Run(request)
+	assert.Equal(t, ErrorBucketOrObjectNotMatching, err)
+
+	server.hook = nil
+	request.Format = InventoryCSV
+	output = open()
+	request.Output = output
+	report, err := inventory.Run(request)
+	assert.Nil(t, err)
+	assert.Nil(t, output.Close())
+	assert.Equal(t, int64(6), report.Total.Objects)
+	assert.NoFileExists(t, request.CheckpointFilePath)
+
+	data, err := ioutil.ReadFile(outputPath)
+	assert.Nil(t, err)
+	out := string(data)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Equal(t, "key,size,etag,last_modified,owner", lines[0])
+	keys := map[string]int{}
+	for _, line := range lines[1:] {
+		keys[strings.Split(line, ",")[0]]++
+	}
+	assert.Equal(t, map[string]int{"a/1": 1, "a/2": 1, "a/b/3": 1, "b/1": 1, "b/c/d/4": 1, "top": 1}, keys)
+	assert.Contains(t, out, "a/b/3,1048576,etag-a/b/3,"+now.Add(-400*24*time.Hour).Format(time.RFC3339)+",")
+}
+
+func TestInventory_Labels(t *testing.T) {
+	assert.Equal(t, "a/b/", prefixKey("a/b/c", 2))
+	assert.Equal(t, "a/", prefixKey("a/b", 2))
+	assert.Equal(t, "", prefixKey("a", 1))
+	assert.Equal(t, ">=1GiB", sizeLabel(1<<30, DefaultSizeBuckets))
+	assert.Equal(t, "1000B-1500B", sizeLabel(1200, []int64{1000, 1500}))
+	assert.Equal(t, "<12h0m0s", ageLabel(time.Hour, []time.Duration{12 * time.Hour}))
+}
@@ -0,0 +1,543 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"golang.org/x/time/rate"
+)
+
+// HTTPHeaderReplicationSourceETag is user metadata of a replica keeping ETag of its source,
+// it tells a replica is up to date when their ETag differs after multipart upload
+const HTTPHeaderReplicationSourceETag = fds.XiaomiMetaPrefix + "replication-source-etag"
+
+// replicaSkippedMetadata is metadata maintained by server, which is not copied to replicas
+var replicaSkippedMetadata = map[string]bool{
+	fds.HTTPHeaderContentLength:         true,
+	fds.HTTPHeaderLastModified:          true,
+	fds.HTTPHeaderContentMD5:            true,
+	fds.HTTPHeaderLastChecked:           true,
+	fds.HTTPHeaderUploadTime:            true,
+	fds.HTTPHeaderDate:                  true,
+	fds.HTTPHeaderAuthorization:         true,
+	fds.HTTPHeaderRange:                 true,
+	fds.HTTPHeaderContentRange:          true,
+	fds.HTTPHeaderETag:                  true,
+	fds.HTTPHeaderContentMetadataLength: true,
+	fds.HTTPHeaderOngoingRestore:        true,
+	fds.HTTPHeaderRestoreExpireDate:     true,
+	fds.HTTPHeaderCRC64ECMA:             true,
+	fds.HTTPHeaderMultipartUploadMode:   true,
+}
+
+// Replicator copies objects under a prefix from source client to target client, which may be
+// of another region. Objects are copied by server if both clients share endpoint and access key,
+// or downloaded and uploaded in parts of PartSize otherwise.
+type Replicator struct {
+	logger  fds.Logger
+	source  *fds.Client
+	target  *fds.Client
+	limiter *rate.Limiter
+
+	PartSize    int64
+	Concurrency int
+}
+
+// NewReplicator new a Replicator
+func NewReplicator(source, target *fds.Client, partSize int64, concurrency int) (*Replicator, error) {
+	if partSize < 1 {
+		return nil, ErrorPartSizeSmallerThanOne
+	}
+
+	if concurrency < 1 {
+		return nil, ErrorConcurrencySmallerThanOne
+	}
+
+	return &Replicator{
+		PartSize:    partSize,
+		Concurrency: concurrency,
+
+		source: source,
+		target: target,
+		logger: source.Logger(),
+	}, nil
+}
+
+// SetLimiter sets a limiter shared by all workers, each object takes a token
+func (replicator *Replicator) SetLimiter(limiter *rate.Limiter) {
+	replicator.limiter = limiter
+}
+
+// SetLogger sets logger of replicator, logger of source client is used by default
+func (replicator *Replicator) SetLogger(logger fds.Logger) {
+	replicator.logger = logger
+}
+
+// ReplicateRequest is input of Replicate, an object named SourcePrefix + name is copied
+// to TargetPrefix + name
+type ReplicateRequest struct {
+	SourceBucketName string
+	SourcePrefix     string
+	TargetBucketName string
+	TargetPrefix     string
+
+	// CopyACL sets ACL of each copied object to the ACL of its source
+	CopyACL bool
+	// Verify checks size and ETag of each replica after it's copied
+	Verify bool
+
+	// CheckpointFilePath saves the marker of listing if it's set, and replication resumes from
+	// the marker if the file exists. The file is removed after replication finishes with no
+	// failed object, it's kept otherwise so that running again retries from the first failure.
+	CheckpointFilePath string
+}
+
+// ReplicateAction is what Replicate did to an object
+type ReplicateAction string
+
+// ReplicateAction const
+const (
+	ReplicateCopied  ReplicateAction = "copied"
+	ReplicateSkipped ReplicateAction = "skipped"
+	ReplicateFailed  ReplicateAction = "failed"
+)
+
+// ReplicateResult is result of an object replicated by Replicate
+type ReplicateResult struct {
+	SourceObjectName string
+	TargetObjectName string
+	Size             int64
+	Action           ReplicateAction
+	Err              error
+}
+
+// ReplicateReport is result of Replicate, objects before marker of checkpoint are not counted
+type ReplicateReport struct {
+	Scanned int64
+	Copied  int64
+	Skipped int64
+	Failed  int64
+	// Bytes is total size of copied objects
+	Bytes int64
+	// Results has failed objects only in no particular order, so it doesn't grow with
+	// the number of copied objects
+	Results []ReplicateResult
+}
+
+// replicateCheckpoint is rewritten after all objects of a batch of listing are replicated
+type replicateCheckpoint struct {
+	Version          int    `json:"version"`
+	SourceBucketName string `json:"sourceBucketName"`
+	SourcePrefix     string `json:"sourcePrefix"`
+	TargetBucketName string `json:"targetBucketName"`
+	TargetPrefix     string `json:"targetPrefix"`
+	Marker           string `json:"marker"`
+}
+
+// replicateBatch is a batch of listing, which is done when pending reaches 0 after it's listed.
+// Marker doesn't move over a failed batch, so failed objects are retried after resuming.
+type replicateBatch struct {
+	nextMarker string
+	pending    int
+	listed     bool
+	failed     bool
+}
+
+// replicateJob tracks batches to advance marker of checkpoint in order of listing. Batches
+// aren't tracked any more once the first of them fails, because marker stays before it till
+// the end of the job, so the list is bounded by batches in progress.
+type replicateJob struct {
+	mu      sync.Mutex
+	logger  fds.Logger
+	request *ReplicateRequest
+	report  *ReplicateReport
+	cp      *replicateCheckpoint
+	batches []*replicateBatch
+	stalled bool
+	err     error
+}
+
+// Replicate copies objects under SourcePrefix which are missing in target, or whose replica
+// differs in size or ETag. Failures of single objects are put into report and don't stop others.
+func (replicator *Replicator) Replicate(request *ReplicateRequest) (*ReplicateReport, error) {
+	return replicator.ReplicateWithContext(context.Background(), request)
+}
+
+// ReplicateWithContext copies objects under SourcePrefix to target with context controlling
+func (replicator *Replicator) ReplicateWithContext(ctx context.Context, request *ReplicateRequest) (report *ReplicateReport, err error) {
+	ctx, span := fds.StartSpan(ctx, replicator.source.Configuration.Tracer, "fds.Replicate")
+	span.SetTag(fds.TraceTagBucket, request.SourceBucketName)
+	defer func() {
+		fds.FinishSpan(span, err)
+	}()
+
+	job := &replicateJob{
+		logger:  replicator.logger,
+		request: request,
+		report:  &ReplicateReport{},
+		cp: &replicateCheckpoint{
+			Version:          checkpointVersion,
+			SourceBucketName: request.SourceBucketName,
+			SourcePrefix:     request.SourcePrefix,
+			TargetBucketName: request.TargetBucketName,
+			TargetPrefix:     request.TargetPrefix,
+		},
+	}
+	if err := job.load(); err != nil {
+		return nil, err
+	}
+
+	type task struct {
+		batch  *replicateBatch
+		object *fds.ObjectSummary
+	}
+	tasks := make(chan task)
+
+	var wg sync.WaitGroup
+	for i := 0; i < replicator.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				result := replicator.replicateObject(ctx, request, t.object)
+				job.done(t.batch, result)
+			}
+		}()
+	}
+
+	err = func() error {
+		listing, err := replicator.list(ctx, request, job.cp.Marker)
+		for {
+			if err != nil {
+				return err
+			}
+			batch := job.add(listing)
+			for i := range listing.ObjectSummaries {
+				select {
+				case tasks <- task{batch: batch, object: &listing.ObjectSummaries[i]}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			job.listed(batch)
+			if !listing.Truncated {
+				return nil
+			}
+			listing, err = replicator.source.ListObjectsNextBatchWithContext(ctx, listing)
+		}
+	}()
+	close(tasks)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = job.err
+	}
+	if err == nil && job.report.Failed == 0 && request.CheckpointFilePath != "" {
+		os.Remove(request.CheckpointFilePath)
+	}
+	return job.report, err
+}
+
+// list lists source from marker
+func (replicator *Replicator) list(ctx context.Context, request *ReplicateRequest, marker string) (*fds.ObjectListing, error) {
+	if marker != "" {
+		return replicator.source.ListObjectsNextBatchWithContext(ctx, &fds.ObjectListing{
+			BucketName: request.SourceBucketName,
+			Prefix:     request.SourcePrefix,
+			MaxKeys:    fds.DefaultListObjectsMaxKeys,
+			NextMarker: marker,
+		})
+	}
+	return replicator.source.ListObjectsWithContext(ctx, &fds.ListObjectsRequest{
+		BucketName: request.SourceBucketName,
+		Prefix:     request.SourcePrefix,
+		MaxKeys:    fds.DefaultListObjectsMaxKeys,
+	})
+}
+
+// replicateObject copies object if its replica is missing or outdated
+func (replicator *Replicator) replicateObject(ctx context.Context, request *ReplicateRequest, object *fds.ObjectSummary) ReplicateResult {
+	result := ReplicateResult{
+		SourceObjectName: object.ObjectName,
+		TargetObjectName: request.TargetPrefix + strings.TrimPrefix(object.ObjectName, request.SourcePrefix),
+		Size:             object.Size,
+		Action:           ReplicateFailed,
+	}
+
+	if replicator.limiter != nil {
+		if result.Err = replicator.limiter.Wait(ctx); result.Err != nil {
+			return result
+		}
+	}
+
+	replica, err := replicator.target.GetObjectMetadataWithContext(ctx, request.TargetBucketName, result.TargetObjectName)
+	if err == nil && isReplicaOf(replica, object) {
+		result.Action = ReplicateSkipped
+		return result
+	}
+	if err != nil && !isNotFound(err) {
+		result.Err = err
+		return result
+	}
+
+	if replicator.sameAccount() {
+		result.Err = replicator.target.CopyObjectWithContext(ctx, &fds.CopyObjectRequest{
+			SourceBucketName: request.SourceBucketName,
+			SourceObjectName: object.ObjectName,
+			TargetBucketName: request.TargetBucketName,
+			TargetObjectName: result.TargetObjectName,
+		})
+	} else {
+		result.Err = replicator.stream(ctx, request, object, result.TargetObjectName)
+	}
+
+	if result.Err == nil && request.CopyACL {
+		result.Err = replicator.copyACL(ctx, request, object.ObjectName, result.TargetObjectName)
+	}
+	if result.Err == nil && request.Verify {
+		replica, err := replicator.target.GetObjectMetadataWithContext(ctx, request.TargetBucketName, result.TargetObjectName)
+		if err != nil {
+			result.Err = err
+		} else if !isReplicaOf(replica, object) {
+			result.Err = ErrorReplicaNotMatching
+		}
+	}
+	if result.Err == nil {
+		result.Action = ReplicateCopied
+	}
+	return result
+}
+
+// sameAccount tells whether objects can be copied by server
+func (replicator *Replicator) sameAccount() bool {
+	return replicator.source.Configuration.Endpoint == replicator.target.Configuration.Endpoint &&
+		replicator.source.AccessID == replicator.target.AccessID
+}
+
+// stream downloads object once and uploads it by PutObject, or by multipart upload if it's
+// larger than PartSize
+func (replicator *Replicator) stream(ctx context.Context, request *ReplicateRequest, object *fds.ObjectSummary, targetObjectName string) error {
+	metadata, err := replicator.source.GetObjectMetadataWithContext(ctx, request.SourceBucketName, object.ObjectName)
+	if err != nil {
+		return err
+	}
+	metadata = replicaMetadata(metadata, object.ETag)
+
+	body, err := replicator.source.GetObjectWithContext(ctx, &fds.GetObjectRequest{
+		BucketName: request.SourceBucketName,
+		ObjectName: object.ObjectName,
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if object.Size <= replicator.PartSize {
+		_, err := replicator.target.PutObjectWithContext(ctx, &fds.PutObjectRequest{
+			BucketName: request.TargetBucketName,
+			ObjectName: targetObjectName,
+			Data:       io.LimitReader(body, object.Size),
+			Metadata:   metadata,
+		})
+		return err
+	}
+
+	upload, err := replicator.target.InitMultipartUploadWithContext(ctx, &fds.InitMultipartUploadRequest{
+		BucketName: request.TargetBucketName,
+		ObjectName: targetObjectName,
+		Metadata:   metadata,
+	})
+	if err != nil {
+		return err
+	}
+	upload.BucketName, upload.ObjectName = request.TargetBucketName, targetObjectName
+
+	parts := &fds.UploadPartList{}
+	for offset, number := int64(0), 1; offset < object.Size; offset, number = offset+replicator.PartSize, number+1 {
+		size := replicator.PartSize
+		if size > object.Size-offset {
+			size = object.Size - offset
+		}
+		part, err := replicator.target.UploadPartWithContext(ctx, &fds.UploadPartRequest{
+			BucketName: upload.BucketName,
+			ObjectName: upload.ObjectName,
+			UploadID:   upload.UploadID,
+			PartNumber: number,
+			Data:       io.LimitReader(body, size),
+		})
+		if err != nil {
+			replicator.target.AbortMultipartUploadWithContext(context.Background(), upload)
+			return err
+		}
+		parts.UploadPartResultList = append(parts.UploadPartResultList, *part)
+	}
+
+	_, err = replicator.target.CompleteMultipartUploadWithContext(ctx, &fds.CompleteMultipartUploadRequest{
+		BucketName:  upload.BucketName,
+		ObjectName:  upload.ObjectName,
+		UploadID:    upload.UploadID,
+		UploadParts: parts,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		replicator.target.AbortMultipartUploadWithContext(context.Background(), upload)
+	}
+	return err
+}
+
+func (replicator *Replicator) copyACL(ctx context.Context, request *ReplicateRequest, sourceObjectName, targetObjectName string) error {
+	acl, err := replicator.source.GetObjectACLWithContext(ctx, &fds.GetObjectACLRequest{
+		BucketName: request.SourceBucketName,
+		ObjectName: sourceObjectName,
+	})
+	if err != nil {
+		return err
+	}
+	return replicator.target.SetObjectACLWithContext(ctx, &fds.SetObjectACLRequest{
+		BucketName: request.TargetBucketName,
+		ObjectName: targetObjectName,
+		ACL:        acl,
+	})
+}
+
+// replicaMetadata returns metadata of source set by users, with ETag of source
+func replicaMetadata(source *fds.ObjectMetadata, etag string) *fds.ObjectMetadata {
+	metadata := fds.NewObjectMetadata()
+	for k, v := range source.GetRawMetadata() {
+		if !replicaSkippedMetadata[k] {
+			metadata.Set(k, v)
+		}
+	}
+	if etag != "" {
+		metadata.Set(HTTPHeaderReplicationSourceETag, etag)
+	}
+	return metadata
+}
+
+// isReplicaOf tells whether replica has the same size and ETag as object
+func isReplicaOf(replica *fds.ObjectMetadata, object *fds.ObjectSummary) bool {
+	size, err := strconv.ParseInt(replica.Get(fds.HTTPHeaderContentMetadataLength), 10, 64)
+	if err != nil || size != object.Size {
+		return false
+	}
+	return object.ETag == "" || replica.Get(fds.HTTPHeaderETag) == object.ETag ||
+		replica.Get(HTTPHeaderReplicationSourceETag) == object.ETag
+}
+
+func isNotFound(err error) bool {
+	var serverErr *fds.ServerError
+	return errors.As(err, &serverErr) && serverErr.Code() == http.StatusNotFound
+}
+
+// load reads marker from checkpoint file if it's made for the same replication
+func (job *replicateJob) load() error {
+	if job.request.CheckpointFilePath == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(job.request.CheckpointFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	saved := &replicateCheckpoint{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		return err
+	}
+	marker := saved.Marker
+	saved.Marker = ""
+	if *saved != *job.cp {
+		return ErrorBucketOrObjectNotMatching
+	}
+	job.cp.Marker = marker
+	return nil
+}
+
+func (job *replicateJob) add(listing *fds.ObjectListing) *replicateBatch {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	batch := &replicateBatch{nextMarker: listing.NextMarker, pending: len(listing.ObjectSummaries)}
+	if !job.stalled {
+		job.batches = append(job.batches, batch)
+	}
+	return batch
+}
+
+func (job *replicateJob) listed(batch *replicateBatch) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	batch.listed = true
+	job.advance()
+}
+
+func (job *replicateJob) done(batch *replicateBatch, result ReplicateResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	report := job.report
+	report.Scanned++
+	switch result.Action {
+	case ReplicateCopied:
+		report.Copied++
+		report.Bytes += result.Size
+	case ReplicateSkipped:
+		report.Skipped++
+	default:
+		report.Failed++
+		report.Results = append(report.Results, result)
+		batch.failed = true
+		job.logger.Warn("failed to replicate object", fds.Fields{
+			fds.LogFieldOperation: "Replicate",
+			fds.LogFieldBucket:    job.request.SourceBucketName,
+			fds.LogFieldObject:    result.SourceObjectName,
+			fds.LogFieldError:     result.Err,
+		})
+	}
+
+	batch.pending--
+	job.advance()
+}
+
+// advance moves marker over leading batches which are done, and saves checkpoint
+func (job *replicateJob) advance() {
+	advanced := false
+	for len(job.batches) > 0 && job.batches[0].listed && job.batches[0].pending == 0 && !job.batches[0].failed {
+		if job.batches[0].nextMarker != "" {
+			job.cp.Marker = job.batches[0].nextMarker
+			advanced = true
+		}
+		job.batches = job.batches[1:]
+	}
+	if len(job.batches) > 0 && job.batches[0].failed {
+		job.batches = nil
+		job.stalled = true
+	}
+	if !advanced || job.request.CheckpointFilePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(job.cp)
+	if err == nil {
+		path := job.request.CheckpointFilePath
+		if err = writeFileSync(path+".tmp", data); err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+	}
+	if err != nil && job.err == nil {
+		job.err = err
+	}
+}
@@ -0,0 +1,290 @@
+package manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/stretchr/testify/assert"
+)
+
+// replicaStore serves writes and ACL requests in the hook of fakeServer
+type replicaStore struct {
+	server *fakeServer
+
+	mu      sync.Mutex
+	acls    map[string]*fds.AccessControlList
+	parts   map[string][][]byte
+	uploads map[string]map[string]string
+	copies  int
+	puts    int
+}
+
+func newReplicaStore(server *fakeServer) *replicaStore {
+	store := &replicaStore{
+		server:  server,
+		acls:    map[string]*fds.AccessControlList{},
+		parts:   map[string][][]byte{},
+		uploads: map[string]map[string]string{},
+	}
+	server.hook = store.hook
+	return store
+}
+
+func userMetadata(header http.Header) map[string]string {
+	metadata := map[string]string{}
+	for k := range header {
+		key := strings.ToLower(k)
+		if key == fds.HTTPHeaderContentType || (strings.HasPrefix(key, fds.XiaomiMetaPrefix) && key != fds.HTTPHeaderContentMetadataLength) {
+			metadata[key] = header.Get(k)
+		}
+	}
+	return metadata
+}
+
+func (s *replicaStore) save(path string, data []byte, etag string, metadata map[string]string) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	s.server.mu.Lock()
+	s.server.objects[path] = &fakeObject{data: data, etag: etag, metadata: metadata, lastModified: time.Now()}
+	s.server.mu.Unlock()
+}
+
+func (s *replicaStore) hook(w http.ResponseWriter, r *http.Request) bool {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := query["acl"]; ok {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(s.acls[path])
+			return false
+		}
+		acl := &fds.AccessControlList{}
+		json.NewDecoder(r.Body).Decode(acl)
+		s.acls[path] = acl
+		return false
+	}
+	if r.Method != http.MethodPut {
+		return true
+	}
+
+	data, _ := ioutil.ReadAll(r.Body)
+	_, copied := query["cp"]
+	_, uploads := query["uploads"]
+	switch {
+	case copied:
+		s.copies++
+		var source map[string]string
+		json.Unmarshal(data, &source)
+		s.server.mu.Lock()
+		o := *s.server.objects[source["srcBucketName"]+"/"+source["srcObjectName"]]
+		s.server.objects[path] = &o
+		s.server.mu.Unlock()
+	case uploads:
+		s.uploads[path] = userMetadata(r.Header)
+		json.NewEncoder(w).Encode(fds.InitMultipartUploadResponse{UploadID: "upload"})
+	case query.Get("partNumber") != "":
+		s.parts[path] = append(s.parts[path], data)
+		json.NewEncoder(w).Encode(fds.UploadPartResponse{PartSize: int64(len(data))})
+	case query.Get("uploadId") != "":
+		var all []byte
+		for _, part := range s.parts[path] {
+			all = append(all, part...)
+		}
+		s.save(path, all, "multipart", s.uploads[path])
+		w.Write([]byte(`{}`))
+	default:
+		s.puts++
+		s.save(path, data, "put", userMetadata(r.Header))
+		w.Write([]byte(`{}`))
+	}
+	return false
+}
+
+func newReplicaSource(t *testing.T) (*fakeServer, *replicaStore) {
+	source := newFakeServer(t)
+	store := newReplicaStore(source)
+	store.save("bucket/src/a", []byte("0123456789"), "etag-a", map[string]string{
+		fds.HTTPHeaderContentType:           "text/plain",
+		fds.XiaomiMetaPrefix + "color":      "red",
+		fds.HTTPHeaderContentMetadataLength: "10",
+	})
+	store.save("bucket/src/big", []byte(strings.Repeat("x", 25)), "etag-big", nil)
+	store.save("bucket/src/same", []byte("same"), "etag-same", nil)
+	store.save("bucket/other", []byte("other"), "etag-other", nil)
+	store.acls["bucket/src/a"] = &fds.AccessControlList{Grants: []fds.Grant{fds.NewGroupGrant(fds.AllUsers, fds.GrantPermissionRead)}}
+	store.acls["bucket/src/big"] = &fds.AccessControlList{}
+	return source, store
+}
+
+func TestReplicator_Stream(t *testing.T) {
+	source, _ := newReplicaSource(t)
+	target := newFakeServer(t)
+	store := newReplicaStore(target)
+	store.save("backup/dst/same", []byte("same"), "put", map[string]string{HTTPHeaderReplicationSourceETag: "etag-same"})
+
+	_, err := NewReplicator(source.Client(), target.Client(), 0, 1)
+	assert.Equal(t, ErrorPartSizeSmallerThanOne, err)
+	replicator, _ := NewReplicator(source.Client(), target.Client(), 10, 2)
+
+	request := &ReplicateRequest{
+		SourceBucketName: "bucket",
+		SourcePrefix:     "src/",
+		TargetBucketName: "backup",
+		TargetPrefix:     "dst/",
+		CopyACL:          true,
+		Verify:           true,
+	}
+	report, err := replicator.Replicate(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), report.Scanned)
+	assert.Equal(t, int64(2), report.Copied)
+	assert.Equal(t, int64(1), report.Skipped)
+	assert.Equal(t, int64(0), report.Failed, report.Results)
+	assert.Empty(t, report.Results)
+	assert.Equal(t, int64(35), report.Bytes)
+	assert.Equal(t, 0, store.copies)
+
+	a := target.objects["backup/dst/a"]
+	assert.Equal(t, "0123456789", string(a.data))
+	assert.Equal(t, "red", a.metadata[fds.XiaomiMetaPrefix+"color"])
+	assert.Equal(t, "text/plain", a.metadata[fds.HTTPHeaderContentType])
+	assert.Equal(t, "etag-a", a.metadata[HTTPHeaderReplicationSourceETag])
+	assert.True(t, store.acls["backup/dst/a"].IsPublic())
+
+	big := target.objects["backup/dst/big"]
+	assert.Equal(t, strings.Repeat("x", 25), string(big.data))
+	assert.Equal(t, "multipart", big.etag)
+	assert.Equal(t, 3, len(store.parts["backup/dst/big"]))
+	assert.Nil(t, target.objects["backup/dst/other"])
+
+	// nothing is copied again
+	report, err = replicator.Replicate(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), report.Skipped)
+	assert.Equal(t, 1, store.puts)
+}
+
+func TestReplicator_ServerSideCopy(t *testing.T) {
+	server, store := newReplicaSource(t)
+	replicator, _ := NewReplicator(server.Client(), server.Client(), 10, 2)
+
+	report, err := replicator.Replicate(&ReplicateRequest{
+		SourceBucketName: "bucket",
+		SourcePrefix:     "src/",
+		TargetBucketName: "backup",
+		Verify:           true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), report.Copied)
+	assert.Equal(t, 3, store.copies)
+	assert.Equal(t, "etag-big", server.objects["backup/big"].etag)
+}
+
+func TestReplicator_Checkpoint(t *testing.T) {
+	source, store := newReplicaSource(t)
+	source.pageSize = 1
+	target := newFakeServer(t)
+	targetStore := newReplicaStore(target)
+
+	var lists int32
+	var markers []string
+	source.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/bucket" {
+			markers = append(markers, r.URL.Query().Get("marker"))
+			if atomic.AddInt32(&lists, 1) == 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return false
+			}
+		}
+		return store.hook(w, r)
+	}
+
+	replicator, _ := NewReplicator(source.Client(), target.Client(), 10, 1)
+	request := &ReplicateRequest{
+		SourceBucketName:   "bucket",
+		SourcePrefix:       "src/",
+		TargetBucketName:   "backup",
+		CheckpointFilePath: filepath.Join(t.TempDir(), "replicate.cp"),
+	}
+	report, err := replicator.Replicate(request)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(2), report.Copied)
+	assert.FileExists(t, request.CheckpointFilePath)
+
+	report, err = replicator.Replicate(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), report.Scanned)
+	assert.Equal(t, []string{"", "src/a", "src/big", "src/big"}, markers)
+	assert.NoFileExists(t, request.CheckpointFilePath)
+
+	// checkpoint is kept if any object fails
+	request.CheckpointFilePath = filepath.Join(t.TempDir(), "failed.cp")
+	request.TargetBucketName = "failed"
+	target.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/failed/same" {
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		}
+		return targetStore.hook(w, r)
+	}
+	report, err = replicator.Replicate(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), report.Failed)
+	assert.Equal(t, 1, len(report.Results))
+	assert.Equal(t, "src/same", report.Results[0].SourceObjectName)
+	assert.FileExists(t, request.CheckpointFilePath)
+
+	target.hook = targetStore.hook
+	report, err = replicator.Replicate(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), report.Copied)
+	assert.NoFileExists(t, request.CheckpointFilePath)
+
+	request.TargetBucketName = "other"
+	assert.Nil(t, ioutil.WriteFile(request.CheckpointFilePath, []byte(`{"version":1,"sourceBucketName":"bucket"}`), 0644))
+	_, err = replicator.Replicate(request)
+	assert.Equal(t, ErrorBucketOrObjectNotMatching, err)
+}
+
+func TestReplicateJob_FailedBatch(t *testing.T) {
+	job := &replicateJob{
+		logger:  fds.NopLogger{},
+		request: &ReplicateRequest{},
+		report:  &ReplicateReport{},
+		cp:      &replicateCheckpoint{},
+	}
+	listing := func(marker string) *fds.ObjectListing {
+		return &fds.ObjectListing{NextMarker: marker, ObjectSummaries: make([]fds.ObjectSummary, 1)}
+	}
+
+	first := job.add(listing("a"))
+	job.listed(first)
+	job.done(first, ReplicateResult{Action: ReplicateCopied})
+	assert.Equal(t, "a", job.cp.Marker)
+
+	failed := job.add(listing("b"))
+	job.listed(failed)
+	job.done(failed, ReplicateResult{Action: ReplicateFailed})
+
+	// marker stays before the failed batch, so later batches aren't tracked
+	for _, marker := range []string{"c", "d"} {
+		batch := job.add(listing(marker))
+		job.listed(batch)
+		job.done(batch, ReplicateResult{Action: ReplicateCopied})
+	}
+	assert.Equal(t, "a", job.cp.Marker)
+	assert.Empty(t, job.batches)
+	assert.Equal(t, int64(1), job.report.Failed)
+	assert.Equal(t, 1, len(job.report.Results))
+}
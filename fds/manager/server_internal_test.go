@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds"
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+)
+
+// fakeObject is an object stored in fakeServer
+type fakeObject struct {
+	data         []byte
+	lastModified time.Time
+	etag         string
+	// metadata is returned as headers of GetObjectMetadata
+	metadata map[string]string
+}
+
+// fakeServer is an in-memory FDS server for unit tests
+type fakeServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+
+	// pageSize limits entries of a ListObjects batch, all entries are returned if it's 0
+	pageSize int
+
+	// hook is called before serving each request, it stops the request if it returns false
+	hook func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{
+		objects: map[string]*fakeObject{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a fds.Client requesting the fake server
+func (s *fakeServer) Client() *fds.Client {
+	conf, _ := fds.NewClientConfiguration(strings.TrimPrefix(s.URL, "http://"))
+	conf.EnableHTTPS = false
+	return fds.New("ak", "sk", conf)
+}
+
+func (s *fakeServer) PutObject(bucketName, objectName string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[bucketName+"/"+objectName] = &fakeObject{
+		data:         data,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	if s.hook != nil && !s.hook(w, r) {
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if r.Method == http.MethodGet && path != "" && !strings.Contains(path, "/") {
+		s.listObjects(w, path, r.URL.Query())
+		return
+	}
+
+	s.mu.Lock()
+	o, ok := s.objects[path]
+	s.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		if _, ok := r.URL.Query()["metadata"]; ok {
+			w.Header().Set(fds.HTTPHeaderContentMetadataLength, strconv.Itoa(len(o.data)))
+			w.Header().Set(fds.HTTPHeaderLastModified, o.lastModified.Format(http.TimeFormat))
+			if o.etag != "" {
+				w.Header().Set(fds.HTTPHeaderETag, o.etag)
+			}
+			for k, v := range o.metadata {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		ranges, err := httpparser.Range(r.Header.Get(fds.HTTPHeaderRange))
+		if err != nil || len(ranges) > 1 {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if len(ranges) == 0 {
+			w.WriteHeader(http.StatusOK)
+			w.Write(o.data)
+			return
+		}
+
+		resolved, err := ranges[0].Resolve(int64(len(o.data)))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		cr := httpparser.ContentRange{Start: resolved.Start, End: resolved.End, Size: int64(len(o.data))}
+		w.Header().Set(fds.HTTPHeaderContentRange, cr.String())
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(o.data[resolved.Start : resolved.End+1])
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// listObjects serves ListObjects of bucket, a common prefix is an entry of batch as an object
+func (s *fakeServer) listObjects(w http.ResponseWriter, bucketName string, query url.Values) {
+	prefix, delimiter, marker := query.Get("prefix"), query.Get("delimiter"), query.Get("marker")
+
+	s.mu.Lock()
+	var names []string
+	for key := range s.objects {
+		name := strings.TrimPrefix(key, bucketName+"/")
+		if name != key && strings.HasPrefix(name, prefix) && name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	listing := &fds.ObjectListing{BucketName: bucketName, Prefix: prefix, Delimiter: delimiter, Marker: marker}
+	entries := 0
+	for _, name := range names {
+		if s.pageSize > 0 && entries == s.pageSize {
+			listing.Truncated = true
+			break
+		}
+		if i := strings.Index(name[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			cp := name[:len(prefix)+i+len(delimiter)]
+			if cp <= marker || (len(listing.CommonPrefixes) > 0 && listing.CommonPrefixes[len(listing.CommonPrefixes)-1] == cp) {
+				continue
+			}
+			listing.CommonPrefixes = append(listing.CommonPrefixes, cp)
+			listing.NextMarker = cp
+		} else {
+			o := s.objects[bucketName+"/"+name]
+			listing.ObjectSummaries = append(listing.ObjectSummaries, fds.ObjectSummary{
+				ObjectName:   name,
+				Size:         int64(len(o.data)),
+				ETag:         o.etag,
+				LastModified: o.lastModified,
+			})
+			listing.NextMarker = name
+		}
+		entries++
+	}
+	s.mu.Unlock()
+
+	json.NewEncoder(w).Encode(listing)
+}
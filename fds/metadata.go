@@ -0,0 +1,335 @@
+package fds
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// predefinedMetaKeys are keys of predefinedMetadata with XiaomiMetaPrefix, which are not user metadata
+var predefinedMetaKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	for k := range predefinedMetadata {
+		if strings.HasPrefix(k, XiaomiMetaPrefix) {
+			keys[k] = true
+		}
+	}
+	return keys
+}()
+
+// UserMetadata is user defined metadata of object, keys are without XiaomiMetaPrefix
+type UserMetadata map[string]string
+
+// parseMetadataTime accepts HTTP date, RFC 3339 and milliseconds since epoch
+func parseMetadataTime(v string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func formatMillis(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
+
+func (metadata *ObjectMetadata) getTime(k string) (time.Time, error) {
+	return parseMetadataTime(metadata.Get(k))
+}
+
+// GetCacheControl gets Cache-Control of object
+func (metadata *ObjectMetadata) GetCacheControl() string {
+	return metadata.Get(HTTPHeaderCacheControl)
+}
+
+// SetCacheControl sets Cache-Control of object
+func (metadata *ObjectMetadata) SetCacheControl(cacheControl string) {
+	metadata.Set(HTTPHeaderCacheControl, cacheControl)
+}
+
+// GetContentEncoding gets Content-Encoding of object
+func (metadata *ObjectMetadata) GetContentEncoding() string {
+	return metadata.Get(HTTPHeaderContentEncoding)
+}
+
+// SetContentEncoding sets Content-Encoding of object
+func (metadata *ObjectMetadata) SetContentEncoding(contentEncoding string) {
+	metadata.Set(HTTPHeaderContentEncoding, contentEncoding)
+}
+
+// GetContentMD5 gets Content-MD5 of object
+func (metadata *ObjectMetadata) GetContentMD5() string {
+	return metadata.Get(HTTPHeaderContentMD5)
+}
+
+// SetContentMD5 sets Content-MD5 of object
+func (metadata *ObjectMetadata) SetContentMD5(md5 string) {
+	metadata.Set(HTTPHeaderContentMD5, md5)
+}
+
+// GetETag gets ETag of object
+func (metadata *ObjectMetadata) GetETag() string {
+	return metadata.Get(HTTPHeaderETag)
+}
+
+// SetETag sets ETag of object
+func (metadata *ObjectMetadata) SetETag(etag string) {
+	metadata.Set(HTTPHeaderETag, etag)
+}
+
+// GetLastModified gets Last-Modified of object
+func (metadata *ObjectMetadata) GetLastModified() (time.Time, error) {
+	return metadata.getTime(HTTPHeaderLastModified)
+}
+
+// SetLastModified sets Last-Modified of object in HTTP date
+func (metadata *ObjectMetadata) SetLastModified(t time.Time) {
+	metadata.Set(HTTPHeaderLastModified, t.UTC().Format(http.TimeFormat))
+}
+
+// GetLastChecked gets last-checked of object
+func (metadata *ObjectMetadata) GetLastChecked() (time.Time, error) {
+	return metadata.getTime(HTTPHeaderLastChecked)
+}
+
+// SetLastChecked sets last-checked of object in milliseconds
+func (metadata *ObjectMetadata) SetLastChecked(t time.Time) {
+	metadata.Set(HTTPHeaderLastChecked, formatMillis(t))
+}
+
+// GetUploadTime gets upload-time of object
+func (metadata *ObjectMetadata) GetUploadTime() (time.Time, error) {
+	return metadata.getTime(HTTPHeaderUploadTime)
+}
+
+// SetUploadTime sets upload-time of object in milliseconds
+func (metadata *ObjectMetadata) SetUploadTime(t time.Time) {
+	metadata.Set(HTTPHeaderUploadTime, formatMillis(t))
+}
+
+// GetDate gets Date of response
+func (metadata *ObjectMetadata) GetDate() (time.Time, error) {
+	return metadata.getTime(HTTPHeaderDate)
+}
+
+// SetDate sets Date in HTTP date
+func (metadata *ObjectMetadata) SetDate(t time.Time) {
+	metadata.Set(HTTPHeaderDate, t.UTC().Format(http.TimeFormat))
+}
+
+// GetRange gets Range
+func (metadata *ObjectMetadata) GetRange() string {
+	return metadata.Get(HTTPHeaderRange)
+}
+
+// SetRange sets Range
+func (metadata *ObjectMetadata) SetRange(r string) {
+	metadata.Set(HTTPHeaderRange, r)
+}
+
+// GetContentRange gets Content-Range
+func (metadata *ObjectMetadata) GetContentRange() string {
+	return metadata.Get(HTTPHeaderContentRange)
+}
+
+// SetContentRange sets Content-Range
+func (metadata *ObjectMetadata) SetContentRange(r string) {
+	metadata.Set(HTTPHeaderContentRange, r)
+}
+
+// GetServerSideEncryption gets server side encryption of object
+func (metadata *ObjectMetadata) GetServerSideEncryption() string {
+	return metadata.Get(HTTPHeaderServerSideEncryption)
+}
+
+// SetServerSideEncryption sets server side encryption of object
+func (metadata *ObjectMetadata) SetServerSideEncryption(encryption string) {
+	metadata.Set(HTTPHeaderServerSideEncryption, encryption)
+}
+
+// GetStorageClass gets storage class of object, Standard is returned if it's not set
+func (metadata *ObjectMetadata) GetStorageClass() StorageClass {
+	if class := metadata.Get(HTTPHeaderStorageClass); class != "" {
+		return StorageClass(class)
+	}
+	return Standard
+}
+
+// SetStorageClass sets storage class of object
+func (metadata *ObjectMetadata) SetStorageClass(class StorageClass) {
+	metadata.Set(HTTPHeaderStorageClass, string(class))
+}
+
+// GetOngoingRestore tells whether object is being restored from archive
+func (metadata *ObjectMetadata) GetOngoingRestore() bool {
+	ongoing, _ := strconv.ParseBool(metadata.Get(HTTPHeaderOngoingRestore))
+	return ongoing
+}
+
+// SetOngoingRestore sets whether object is being restored from archive
+func (metadata *ObjectMetadata) SetOngoingRestore(ongoing bool) {
+	metadata.Set(HTTPHeaderOngoingRestore, strconv.FormatBool(ongoing))
+}
+
+// GetRestoreExpireDate gets when restored copy of archived object expires
+func (metadata *ObjectMetadata) GetRestoreExpireDate() (time.Time, error) {
+	return metadata.getTime(HTTPHeaderRestoreExpireDate)
+}
+
+// SetRestoreExpireDate sets when restored copy of archived object expires in milliseconds
+func (metadata *ObjectMetadata) SetRestoreExpireDate(t time.Time) {
+	metadata.Set(HTTPHeaderRestoreExpireDate, formatMillis(t))
+}
+
+// GetCRC64ECMA gets CRC-64/ECMA of object
+func (metadata *ObjectMetadata) GetCRC64ECMA() (uint64, error) {
+	return strconv.ParseUint(metadata.Get(HTTPHeaderCRC64ECMA), 10, 64)
+}
+
+// SetCRC64ECMA sets CRC-64/ECMA of object
+func (metadata *ObjectMetadata) SetCRC64ECMA(crc uint64) {
+	metadata.Set(HTTPHeaderCRC64ECMA, strconv.FormatUint(crc, 10))
+}
+
+// GetMultipartUploadMode gets multipart upload mode, ModeMultiBlob or ModeDierect
+func (metadata *ObjectMetadata) GetMultipartUploadMode() string {
+	return metadata.Get(HTTPHeaderMultipartUploadMode)
+}
+
+// SetMultipartUploadMode sets multipart upload mode, ModeMultiBlob or ModeDierect
+func (metadata *ObjectMetadata) SetMultipartUploadMode(mode string) {
+	metadata.Set(HTTPHeaderMultipartUploadMode, mode)
+}
+
+// GetUserMetadata gets user metadata of key without XiaomiMetaPrefix
+func (metadata *ObjectMetadata) GetUserMetadata(key string) string {
+	return metadata.Get(XiaomiMetaPrefix + key)
+}
+
+// SetUserMetadata sets user metadata of key without XiaomiMetaPrefix
+func (metadata *ObjectMetadata) SetUserMetadata(key, value string) error {
+	k := XiaomiMetaPrefix + strings.ToLower(key)
+	if predefinedMetaKeys[k] {
+		return ErrorInvalidUserMetadata
+	}
+	return metadata.Set(k, value)
+}
+
+// UserMetadata returns a copy of user metadata, predefined metadata with
+// XiaomiMetaPrefix, e.g. storage class, is not included
+func (metadata *ObjectMetadata) UserMetadata() UserMetadata {
+	result := UserMetadata{}
+	for k, v := range metadata.metadata {
+		if strings.HasPrefix(k, XiaomiMetaPrefix) && !predefinedMetaKeys[k] {
+			result[strings.TrimPrefix(k, XiaomiMetaPrefix)] = v
+		}
+	}
+	return result
+}
+
+// ReplaceUserMetadata removes all user metadata and sets the given ones
+func (metadata *ObjectMetadata) ReplaceUserMetadata(userMetadata UserMetadata) error {
+	for k := range metadata.UserMetadata() {
+		delete(metadata.metadata, XiaomiMetaPrefix+k)
+	}
+	for k, v := range userMetadata {
+		if err := metadata.SetUserMetadata(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectMetadataJSON is JSON form of ObjectMetadata, which is also the form of SetObjectMetadata
+type objectMetadataJSON struct {
+	RawMeta map[string]string `json:"rawMeta"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (metadata *ObjectMetadata) MarshalJSON() ([]byte, error) {
+	raw := metadata.metadata
+	if raw == nil {
+		raw = map[string]string{}
+	}
+	return json.Marshal(objectMetadataJSON{RawMeta: raw})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (metadata *ObjectMetadata) UnmarshalJSON(data []byte) error {
+	var v objectMetadataJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	metadata.metadata = map[string]string{}
+	for k, value := range v.RawMeta {
+		if err := metadata.Set(k, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HeadObjectResponse is output of HeadObject
+type HeadObjectResponse struct {
+	BucketName           string
+	ObjectName           string
+	ETag                 string
+	Size                 int64
+	ContentType          string
+	ContentEncoding      string
+	CacheControl         string
+	LastModified         time.Time
+	UploadTime           time.Time
+	StorageClass         StorageClass
+	OngoingRestore       bool
+	RestoreExpireDate    time.Time
+	CRC64ECMA            uint64
+	ServerSideEncryption string
+	UserMetadata         UserMetadata
+
+	// Metadata is all metadata of object
+	Metadata *ObjectMetadata
+}
+
+// newHeadObjectResponse fills HeadObjectResponse from metadata, missing or malformed fields are left zero
+func newHeadObjectResponse(bucketName, objectName string, metadata *ObjectMetadata) *HeadObjectResponse {
+	result := &HeadObjectResponse{
+		BucketName:           bucketName,
+		ObjectName:           objectName,
+		ETag:                 metadata.GetETag(),
+		ContentType:          metadata.GetContentType(),
+		ContentEncoding:      metadata.GetContentEncoding(),
+		CacheControl:         metadata.GetCacheControl(),
+		StorageClass:         metadata.GetStorageClass(),
+		OngoingRestore:       metadata.GetOngoingRestore(),
+		ServerSideEncryption: metadata.GetServerSideEncryption(),
+		UserMetadata:         metadata.UserMetadata(),
+		Metadata:             metadata,
+	}
+	result.Size, _ = metadata.GetContentLength()
+	result.LastModified, _ = metadata.GetLastModified()
+	result.UploadTime, _ = metadata.GetUploadTime()
+	result.RestoreExpireDate, _ = metadata.GetRestoreExpireDate()
+	result.CRC64ECMA, _ = metadata.GetCRC64ECMA()
+	return result
+}
+
+// HeadObject gets metadata of object as a struct
+func (client *Client) HeadObject(bucketName, objectName string) (*HeadObjectResponse, error) {
+	return client.HeadObjectWithContext(context.Background(), bucketName, objectName)
+}
+
+// HeadObjectWithContext gets metadata of object as a struct with context controlling
+func (client *Client) HeadObjectWithContext(ctx context.Context, bucketName, objectName string) (*HeadObjectResponse, error) {
+	metadata, err := client.GetObjectMetadataWithContext(ctx, bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return newHeadObjectResponse(bucketName, objectName, metadata), nil
+}
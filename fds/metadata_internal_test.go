@@ -0,0 +1,102 @@
+package fds
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectMetadata_Typed(t *testing.T) {
+	lastModified := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	uploadTime := time.Unix(1760860800, 123*int64(time.Millisecond))
+
+	header := http.Header{}
+	header.Set(HTTPHeaderLastModified, lastModified.Format(http.TimeFormat))
+	header.Set(HTTPHeaderUploadTime, "1760860800123")
+	header.Set(HTTPHeaderContentMetadataLength, "1024")
+	header.Set(HTTPHeaderStorageClass, string(Archive))
+	header.Set(HTTPHeaderOngoingRestore, "true")
+	header.Set(HTTPHeaderCRC64ECMA, "18446744073709551615")
+	header.Set(XiaomiMetaPrefix+"Owner", "alice")
+	metadata := parseObjectMetadataFromHeader(header)
+
+	v, err := metadata.GetLastModified()
+	assert.Nil(t, err)
+	assert.True(t, lastModified.Equal(v))
+	v, err = metadata.GetUploadTime()
+	assert.Nil(t, err)
+	assert.True(t, uploadTime.Equal(v))
+	length, err := metadata.GetContentLength()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1024), length)
+	assert.Equal(t, Archive, metadata.GetStorageClass())
+	assert.True(t, metadata.GetOngoingRestore())
+	crc, err := metadata.GetCRC64ECMA()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(18446744073709551615), crc)
+
+	_, err = metadata.GetRestoreExpireDate()
+	assert.NotNil(t, err)
+	assert.Equal(t, Standard, NewObjectMetadata().GetStorageClass())
+
+	metadata.SetUploadTime(uploadTime)
+	assert.Equal(t, "1760860800123", metadata.Get(HTTPHeaderUploadTime))
+	metadata.SetLastModified(lastModified)
+	assert.Equal(t, "Mon, 19 Oct 2026 08:00:00 GMT", metadata.Get(HTTPHeaderLastModified))
+}
+
+func TestObjectMetadata_UserMetadata(t *testing.T) {
+	metadata := NewObjectMetadata()
+	metadata.SetContentLength(10)
+	metadata.SetStorageClass(StandardInfrequentAccess)
+	assert.Nil(t, metadata.SetUserMetadata("Owner", "alice"))
+	assert.Equal(t, ErrorInvalidUserMetadata, metadata.SetUserMetadata("storage-class", "x"))
+
+	assert.Equal(t, "alice", metadata.GetUserMetadata("owner"))
+	assert.Equal(t, "alice", metadata.Get(XiaomiMetaPrefix+"owner"))
+	assert.Equal(t, UserMetadata{"owner": "alice"}, metadata.UserMetadata())
+
+	assert.Nil(t, metadata.ReplaceUserMetadata(UserMetadata{"team": "storage"}))
+	assert.Equal(t, UserMetadata{"team": "storage"}, metadata.UserMetadata())
+	assert.Equal(t, StandardInfrequentAccess, metadata.GetStorageClass())
+}
+
+func TestObjectMetadata_JSON(t *testing.T) {
+	metadata := NewObjectMetadata()
+	metadata.SetContentType("text/plain")
+	metadata.SetUserMetadata("owner", "alice")
+
+	data, err := json.Marshal(metadata)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"rawMeta":{"content-type":"text/plain","x-xiaomi-meta-owner":"alice"}}`, string(data))
+
+	decoded := &ObjectMetadata{}
+	assert.Nil(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, metadata.GetRawMetadata(), decoded.GetRawMetadata())
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"rawMeta":{"unknown":"x"}}`), decoded))
+}
+
+func TestHeadObject(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Query(), "metadata")
+		w.Header().Set(HTTPHeaderETag, "abc")
+		w.Header().Set(HTTPHeaderContentType, "text/plain")
+		w.Header().Set(HTTPHeaderContentMetadataLength, "5")
+		w.Header().Set(HTTPHeaderLastModified, "Mon, 19 Oct 2026 08:00:00 GMT")
+		w.Header().Set(XiaomiMetaPrefix+"owner", "alice")
+	})
+
+	result, err := client.HeadObject("bucket", "object")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", result.ETag)
+	assert.Equal(t, int64(5), result.Size)
+	assert.Equal(t, "text/plain", result.ContentType)
+	assert.Equal(t, Standard, result.StorageClass)
+	assert.Equal(t, UserMetadata{"owner": "alice"}, result.UserMetadata)
+	assert.Equal(t, 2026, result.LastModified.Year())
+	assert.True(t, result.UploadTime.IsZero())
+}
@@ -0,0 +1,182 @@
+package fds
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/XiaoMi/go-fds/cslb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsNamespace is namespace of metrics of Client
+const MetricsNamespace = "fds_client"
+
+// Metric label names
+const (
+	MetricLabelOperation = "operation"
+	MetricLabelBucket    = "bucket"
+	MetricLabelStatus    = "status"
+	MetricLabelHost      = "host"
+)
+
+// Metrics collects prometheus metrics of Client, it's opt-in by setting
+// ClientConfiguration.Metrics. All methods of a nil Metrics do nothing.
+type Metrics struct {
+	registerer prometheus.Registerer
+
+	requests      *prometheus.CounterVec
+	latency       *prometheus.HistogramVec
+	bytesSent     *prometheus.CounterVec
+	bytesReceived *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+	inFlight      *prometheus.GaugeVec
+	partLatency   *prometheus.HistogramVec
+	clockSkew     prometheus.Gauge
+}
+
+// NewMetrics creates Metrics and registers its collectors into registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		registerer: registerer,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "requests_total",
+			Help:      "Total number of FDS requests by status class",
+		}, []string{MetricLabelOperation, MetricLabelBucket, MetricLabelStatus}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of FDS requests till response header is received",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{MetricLabelOperation, MetricLabelBucket}),
+		bytesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "sent_bytes_total",
+			Help:      "Total bytes of FDS request bodies",
+		}, []string{MetricLabelOperation, MetricLabelBucket}),
+		bytesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "received_bytes_total",
+			Help:      "Total bytes of FDS response bodies",
+		}, []string{MetricLabelOperation, MetricLabelBucket}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "retries_total",
+			Help:      "Total number of retried FDS requests",
+		}, []string{MetricLabelOperation, MetricLabelBucket}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "in_flight_requests",
+			Help:      "Number of FDS requests waiting for response header",
+		}, []string{MetricLabelOperation}),
+		partLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "part_duration_seconds",
+			Help:      "Duration of transferring a part in multipart upload or concurrent download",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		}, []string{MetricLabelOperation, MetricLabelBucket}),
+		clockSkew: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "clock_skew_seconds",
+			Help:      "Server time minus local time measured from the latest response",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.requests, m.latency, m.bytesSent, m.bytesReceived, m.retries, m.inFlight, m.partLatency, m.clockSkew,
+	} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// statusClass turns status code into 2xx, 4xx... or "error" for failed requests
+func statusClass(code int) string {
+	if code <= 0 {
+		return "error"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// requestStarted is called before sending a request, and returns a function
+// which is called after response header is received
+func (m *Metrics) requestStarted(operation, bucketName string, sent int64) func(code int) {
+	if m == nil {
+		return func(int) {}
+	}
+
+	start := time.Now()
+	m.inFlight.WithLabelValues(operation).Inc()
+	return func(code int) {
+		m.inFlight.WithLabelValues(operation).Dec()
+		m.latency.WithLabelValues(operation, bucketName).Observe(time.Since(start).Seconds())
+		m.requests.WithLabelValues(operation, bucketName, statusClass(code)).Inc()
+		if sent > 0 {
+			m.bytesSent.WithLabelValues(operation, bucketName).Add(float64(sent))
+		}
+	}
+}
+
+// countReceived counts bytes read from body
+func (m *Metrics) countReceived(operation, bucketName string, body io.ReadCloser) io.ReadCloser {
+	if m == nil {
+		return body
+	}
+
+	return &countingReadCloser{
+		ReadCloser: body,
+		counter:    m.bytesReceived.WithLabelValues(operation, bucketName),
+	}
+}
+
+// ObserveRetry counts a retried request of operation
+func (m *Metrics) ObserveRetry(operation, bucketName string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(operation, bucketName).Inc()
+}
+
+// ObservePart records duration of transferring a part
+func (m *Metrics) ObservePart(operation, bucketName string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.partLatency.WithLabelValues(operation, bucketName).Observe(duration.Seconds())
+}
+
+// observeClockSkew records skew between server and local time
+func (m *Metrics) observeClockSkew(skew time.Duration) {
+	if m == nil {
+		return
+	}
+	m.clockSkew.Set(skew.Seconds())
+}
+
+// registerLoadBalancer exposes node counters of lb with host label
+func (m *Metrics) registerLoadBalancer(host string, lb *cslb.LoadBalancer) error {
+	if m == nil || lb.Metrics() == nil {
+		return nil
+	}
+
+	registerer := prometheus.WrapRegistererWithPrefix(MetricsNamespace+"_",
+		prometheus.WrapRegistererWith(prometheus.Labels{MetricLabelHost: host}, m.registerer))
+	return registerer.Register(lb.Metrics())
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.counter.Add(float64(n))
+	}
+	return n, err
+}
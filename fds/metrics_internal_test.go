@@ -0,0 +1,67 @@
+package fds
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Client(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/bucket/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	assert.Nil(t, err)
+	conf, _ := NewClientConfiguration(strings.TrimPrefix(server.URL, "http://"))
+	conf.EnableHTTPS = false
+	conf.Metrics = metrics
+	client := New("ak", "sk", conf)
+
+	reader, err := client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	ioutil.ReadAll(reader)
+	reader.Close()
+
+	_, err = client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "missing"})
+	assert.NotNil(t, err)
+
+	_, err = client.PutObject(&PutObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Data:       bytes.NewReader(make([]byte, 100)),
+	})
+	assert.NotNil(t, err) // body is not json
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("GetObject", "bucket", "2xx")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("GetObject", "bucket", "4xx")))
+	assert.Equal(t, float64(10), testutil.ToFloat64(metrics.bytesReceived.WithLabelValues("GetObject", "bucket")))
+	assert.Equal(t, float64(100), testutil.ToFloat64(metrics.bytesSent.WithLabelValues("PutObject", "bucket")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.inFlight.WithLabelValues("GetObject")))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.latency))
+
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	assert.Contains(t, names, "fds_client_load_balancer_node_counter")
+
+	_, err = NewMetrics(registry)
+	assert.NotNil(t, err)
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -19,6 +18,8 @@ type GetObjectRequest struct {
 	BucketName string `param:"-" header:"-"`
 	ObjectName string `param:"-" header:"-"`
 	Range      string `param:"-" header:"Range,omitempty"`
+
+	Progress ProgressListener `param:"-" header:"-"`
 }
 
 // GetObject will get full content of object
@@ -33,14 +34,24 @@ func (client *Client) GetObjectWithContext(ctx context.Context, request *GetObje
 		ObjectName:         request.ObjectName,
 		QueryHeaderOptions: request,
 		Method:             HTTPGet,
+		CDN:                true,
+		Operation:          "GetObject",
 	}
 
+	tracker := NewProgressTracker(request.Progress, request.BucketName, request.ObjectName, -1)
+	tracker.Started()
+
 	resp, err := client.do(ctx, req)
 	if err != nil {
+		tracker.Failed(err)
 		return nil, err
 	}
 
-	return resp.Body, nil
+	if tracker == nil {
+		return resp.Body, nil
+	}
+	tracker.SetTotal(resp.ContentLength)
+	return &progressReader{reader: resp.Body, tracker: tracker, completeOnEOF: true}, nil
 }
 
 // PutObjectRequest is the input of PutObject method
@@ -57,6 +68,8 @@ type PutObjectRequest struct {
 	ContentMd5         string          `header:"Content-Md5,omitempty" param:"-"`
 	Expect             string          `header:"Expect,omitempty" param:"-"`
 	Metadata           *ObjectMetadata `header:"-" param:"-"`
+
+	Progress ProgressListener `header:"-" param:"-"`
 }
 
 // PutObjectResponse is the result of PutObject method
@@ -86,13 +99,18 @@ func (client *Client) PutObjectWithContext(ctx context.Context, request *PutObje
 		Metadata:           request.Metadata,
 		Method:             HTTPPut,
 		Result:             result,
+		Progress:           newUploadProgressTracker(request.Progress, request.BucketName, request.ObjectName, request.Data),
+		Operation:          "PutObject",
 	}
 
+	req.Progress.Started()
 	resp, err := client.do(ctx, req)
 	if err != nil {
+		req.Progress.Failed(err)
 		return result, err
 	}
 	defer resp.Body.Close()
+	req.Progress.Completed()
 
 	return result, nil
 }
@@ -108,6 +126,7 @@ func (client *Client) DoesObjectExistWithContext(ctx context.Context, bucketName
 		BucketName: bucketName,
 		ObjectName: objectName,
 		Method:     HTTPHead,
+		Operation:  "DoesObjectExist",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -158,6 +177,8 @@ func (client *Client) CopyObjectWithContext(ctx context.Context, request *CopyOb
 		QueryHeaderOptions: request,
 		Method:             HTTPPut,
 		Data:               bytes.NewReader(data),
+		Long:               true,
+		Operation:          "CopyObject",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -185,6 +206,7 @@ func (client *Client) RenameObjectWithContext(ctx context.Context, bucketName, s
 		ObjectName:         sourceObjectName,
 		QueryHeaderOptions: renameObjectOption{targetObjectName},
 		Method:             HTTPPut,
+		Operation:          "RenameObject",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -207,6 +229,7 @@ func (client *Client) DeleteObjectWithContext(ctx context.Context, bucketName, o
 		BucketName: bucketName,
 		ObjectName: objectName,
 		Method:     HTTPDelete,
+		Operation:  "DeleteObject",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -240,6 +263,7 @@ func (client *Client) DeleteObjectsWithContext(ctx context.Context, bucketName s
 		Method:             HTTPPut,
 		QueryHeaderOptions: deleteObjectsOption{EnableTrash: put2trash},
 		Data:               bytes.NewReader(data),
+		Operation:          "DeleteObjects",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -312,6 +336,7 @@ var predefinedMetadata = map[string]string{
 	HTTPHeaderAuthorization:         "",
 	HTTPHeaderRange:                 "",
 	HTTPHeaderContentRange:          "",
+	HTTPHeaderETag:                  "",
 	HTTPHeaderContentMetadataLength: "",
 	HTTPHeaderServerSideEncryption:  "",
 	HTTPHeaderStorageClass:          "",
@@ -337,6 +362,9 @@ func (metadata *ObjectMetadata) Set(k, v string) error {
 	key := strings.ToLower(k)
 	_, ok := predefinedMetadata[key]
 	if ok || strings.HasPrefix(key, XiaomiMetaPrefix) {
+		if metadata.metadata == nil {
+			metadata.metadata = map[string]string{}
+		}
 		metadata.metadata[key] = v
 		return nil
 	} else {
@@ -371,15 +399,7 @@ func (metadata *ObjectMetadata) SetContentType(contentType string) {
 }
 
 func (metadata *ObjectMetadata) serialize() ([]byte, error) {
-	data := make(map[string]map[string]string)
-
-	data["rawMeta"] = metadata.metadata
-	result, e := json.Marshal(data)
-	if e != nil {
-		return nil, e
-	}
-
-	return result, nil
+	return json.Marshal(metadata)
 }
 
 func parseObjectMetadataFromHeader(header http.Header) *ObjectMetadata {
@@ -410,6 +430,7 @@ func (client *Client) GetObjectMetadataWithContext(ctx context.Context, bucketNa
 		ObjectName:         objectName,
 		Method:             HTTPGet,
 		QueryHeaderOptions: getObjectMetadataOption{},
+		Operation:          "GetObjectMetadata",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -452,6 +473,7 @@ func (client *Client) SetObjectMetadataWithContext(ctx context.Context, request
 		Method:             HTTPPut,
 		QueryHeaderOptions: request,
 		Data:               bytes.NewReader(data),
+		Operation:          "SetObjectMetadata",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -507,6 +529,7 @@ func (client *Client) ListObjectsWithContext(ctx context.Context, request *ListO
 		Method:             HTTPGet,
 		QueryHeaderOptions: request,
 		Result:             result,
+		Operation:          "ListObjects",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -531,6 +554,7 @@ func (client *Client) ListObjectsNextBatchWithContext(ctx context.Context, previ
 		Method:             HTTPGet,
 		QueryHeaderOptions: previous,
 		Result:             result,
+		Operation:          "ListObjectsNextBatch",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -576,6 +600,7 @@ func (client *Client) InitMultipartUploadWithContext(ctx context.Context, reques
 		Method:             HTTPPut,
 		QueryHeaderOptions: request,
 		Result:             result,
+		Operation:          "InitMultipartUpload",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -594,6 +619,8 @@ type UploadPartRequest struct {
 	UploadID   string    `param:"uploadId" header:"-"`
 	PartNumber int       `param:"partNumber" header:"-"`
 	Data       io.Reader `param:"-" header:"-"`
+
+	Progress ProgressListener `param:"-" header:"-"`
 }
 
 // UploadPartResponse is result of UploadPart
@@ -618,13 +645,21 @@ func (client *Client) UploadPartWithContext(ctx context.Context, request *Upload
 		Data:               request.Data,
 		QueryHeaderOptions: request,
 		Result:             result,
+		Progress:           newUploadProgressTracker(request.Progress, request.BucketName, request.ObjectName, request.Data),
+		Operation:          "UploadPart",
 	}
 
+	start := time.Now()
+	req.Progress.Started()
 	resp, err := client.do(ctx, req)
 	if err != nil {
+		req.Progress.Failed(err)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	client.Configuration.Metrics.ObservePart("UploadPart", request.BucketName, time.Since(start))
+	req.Progress.PartCompleted(request.PartNumber, result.PartSize)
+	req.Progress.Completed()
 
 	return result, err
 }
@@ -676,6 +711,8 @@ func (client *Client) CompleteMultipartUploadWithContext(ctx context.Context, re
 		QueryHeaderOptions: request,
 		Metadata:           request.Metadata,
 		Result:             result,
+		Long:               true,
+		Operation:          "CompleteMultipartUpload",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -699,6 +736,7 @@ func (client *Client) AbortMultipartUploadWithContext(ctx context.Context, reque
 		ObjectName:         request.ObjectName,
 		Method:             HTTPDelete,
 		QueryHeaderOptions: request,
+		Operation:          "AbortMultipartUpload",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -726,6 +764,7 @@ func (client *Client) RestoreObjectWithContext(ctx context.Context, bucketName,
 		ObjectName:         objectName,
 		Method:             HTTPPut,
 		QueryHeaderOptions: restoreObjectOption{},
+		Operation:          "RestoreObject",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -742,42 +781,6 @@ func (client *Client) GenerateAbsoluteObjectURL(bucketName, objectName string) *
 	return client.buildRequestURL(bucketName, objectName, "", false)
 }
 
-// GeneratePresignedURLRequest is input of GeneratePresignedURL
-type GeneratePresignedURLRequest struct {
-	CDN        bool
-	BucketName string
-	ObjectName string
-	Method     HTTPMethod
-	Expiration time.Time
-	Metadata   *ObjectMetadata
-}
-
-// GeneratePresignedURL generates presigned url
-func (client *Client) GeneratePresignedURL(request *GeneratePresignedURLRequest) (*url.URL, error) {
-	baseURL := client.buildRequestURL(request.BucketName, request.ObjectName, "", request.CDN)
-
-	params := url.Values{}
-	if request.Method == HTTPHead {
-		params.Add("metadata", "")
-	}
-
-	params.Add(HTTPHeaderGalaxyAccessKeyID, client.AccessID)
-	params.Add(HTTPHeaderExpires, fmt.Sprintf("%d", request.Expiration.UnixNano()/int64(time.Millisecond)))
-	baseURL.RawQuery = params.Encode()
-
-	header := http.Header{}
-	for k, v := range request.Metadata.metadata {
-		header.Set(k, v)
-	}
-
-	sig, e := signature(client.AccessSecret, request.Method, baseURL.String(), header)
-	if e != nil {
-		return nil, e
-	}
-
-	return url.Parse(baseURL.String() + "&" + HTTPHeaderSignature + "=" + sig)
-}
-
 // GetObjectACLRequest is input of GetObjectACL
 type GetObjectACLRequest struct {
 	aclOption
@@ -800,6 +803,7 @@ func (client *Client) GetObjectACLWithContext(ctx context.Context, request *GetO
 		Method:             HTTPGet,
 		QueryHeaderOptions: request,
 		Result:             result,
+		Operation:          "GetObjectACL",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -838,6 +842,7 @@ func (client *Client) SetObjectACLWithContext(ctx context.Context, request *SetO
 		Method:             HTTPPut,
 		QueryHeaderOptions: request,
 		Data:               bytes.NewReader(aclBytes),
+		Operation:          "SetObjectACL",
 	}
 
 	resp, err := client.do(ctx, req)
@@ -856,16 +861,7 @@ func (client *Client) SetObjectPublic(bucketName, objectName string) error {
 
 // SetObjectPublicWithContext is a shortcut of setting object public with context controlling
 func (client *Client) SetObjectPublicWithContext(ctx context.Context, bucketName, objectName string) error {
-	grant := Grant{
-		Grantee: GrantKey{
-			ID: "ALL_USERS",
-		},
-		Permission: GrantPermissionRead,
-		Type:       GrantTypeGroup,
-	}
-
-	controlList := &AccessControlList{}
-	controlList.AddGrant(grant)
+	controlList := (&AccessControlList{}).MakePublic()
 
 	aclRequest := &SetObjectACLRequest{
 		BucketName: bucketName,
@@ -0,0 +1,78 @@
+package fds
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
+)
+
+// GeneratePostPolicyRequest is input of GeneratePostPolicy
+type GeneratePostPolicyRequest struct {
+	CDN        bool
+	BucketName string
+	// Key is the exact object name, KeyPrefix limits object names chosen by browser if Key is empty
+	Key       string
+	KeyPrefix string
+	// MinContentLength and MaxContentLength limit size of file if MaxContentLength > 0
+	MinContentLength int64
+	MaxContentLength int64
+	// ContentType is the exact Content-Type field, ContentTypePrefix limits it if ContentType is empty, e.g. image/
+	ContentType       string
+	ContentTypePrefix string
+	Expiration        time.Time
+	// Conditions are extra conditions, e.g. signer.Equals("x-xiaomi-meta-owner", "alice")
+	Conditions []signer.PolicyCondition
+}
+
+// PostForm is a signed multipart/form-data upload form, Fields should be sent before
+// the file field named "file". Key and Content-Type fields are preset if they are exact,
+// browser must fill them otherwise.
+type PostForm struct {
+	URL    *url.URL
+	Fields map[string]string
+	Policy *signer.PostPolicy
+}
+
+// GeneratePostPolicy generates a signed POST form for uploading from browsers without Authorization header
+func (client *Client) GeneratePostPolicy(request *GeneratePostPolicyRequest) (*PostForm, error) {
+	policy := &signer.PostPolicy{
+		Expiration: request.Expiration.Add(client.ClockOffset()),
+		Conditions: []signer.PolicyCondition{signer.Equals(signer.FormBucket, request.BucketName)},
+	}
+	fields := map[string]string{}
+
+	if request.Key != "" {
+		policy.Conditions = append(policy.Conditions, signer.Equals(signer.FormKey, request.Key))
+		fields[signer.FormKey] = request.Key
+	} else {
+		policy.Conditions = append(policy.Conditions, signer.StartsWith(signer.FormKey, request.KeyPrefix))
+	}
+
+	if request.ContentType != "" {
+		policy.Conditions = append(policy.Conditions, signer.Equals(HTTPHeaderContentType, request.ContentType))
+		fields[HTTPHeaderContentType] = request.ContentType
+	} else if request.ContentTypePrefix != "" {
+		policy.Conditions = append(policy.Conditions, signer.StartsWith(HTTPHeaderContentType, request.ContentTypePrefix))
+	}
+
+	if request.MaxContentLength > 0 {
+		policy.Conditions = append(policy.Conditions,
+			signer.ContentLengthRange(request.MinContentLength, request.MaxContentLength))
+	}
+	policy.Conditions = append(policy.Conditions, request.Conditions...)
+
+	signed, err := signer.SignPostPolicy(policy, client.AccessID, client.AccessSecret)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range signed {
+		fields[k] = v
+	}
+
+	return &PostForm{
+		URL:    client.buildRequestURL(request.BucketName, "", "", request.CDN),
+		Fields: fields,
+		Policy: policy,
+	}, nil
+}
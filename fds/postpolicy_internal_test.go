@@ -0,0 +1,130 @@
+package fds
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+// postForm posts form with file and overridden fields to form.URL of server, the error of
+// verification is returned
+func postForm(t *testing.T, server string, form *PostForm, override map[string]string, file []byte) error {
+	fields := map[string]string{}
+	for k, v := range form.Fields {
+		fields[k] = v
+	}
+	for k, v := range override {
+		fields[k] = v
+	}
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, k := range keys {
+		writer.WriteField(k, fields[k])
+	}
+	part, _ := writer.CreateFormFile(signer.FormFile, "upload.png")
+	part.Write(file)
+	writer.Close()
+
+	resp, err := http.Post(server+form.URL.Path, writer.FormDataContentType(), &body)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return errors.New(resp.Header.Get("X-Error"))
+}
+
+func TestGeneratePostPolicy(t *testing.T) {
+	verifier := signer.NewVerifier(func(accessKeyID string) (string, error) {
+		return "sk", nil
+	})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := verifier.VerifyPostRequest(r); err != nil {
+			w.Header().Set("X-Error", err.Error())
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	server := "http://" + client.Configuration.Endpoint
+
+	form, err := client.GeneratePostPolicy(&GeneratePostPolicyRequest{
+		BucketName:        "bucket",
+		KeyPrefix:         "ugc/",
+		ContentTypePrefix: "image/",
+		MaxContentLength:  10,
+		Expiration:        time.Now().Add(time.Minute),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/bucket", form.URL.Path)
+	assert.Equal(t, "ak", form.Fields[signer.FormAccessKeyID])
+	decoded, err := signer.DecodePostPolicy(form.Fields[signer.FormPolicy])
+	assert.Nil(t, err)
+	assert.Equal(t, form.Policy.Conditions, decoded.Conditions)
+
+	valid := map[string]string{signer.FormKey: "ugc/a.png", HTTPHeaderContentType: "image/png"}
+	assert.Nil(t, postForm(t, server, form, valid, []byte("png")))
+	assert.Nil(t, postForm(t, server, form, map[string]string{
+		signer.FormKey: "ugc/a.png", HTTPHeaderContentType: "image/png", "x-ignore-csrf": "1",
+	}, []byte("png")))
+
+	err = postForm(t, server, form, valid, bytes.Repeat([]byte("x"), 11))
+	assert.Equal(t, signer.ErrPolicyContentSize.Error(), err.Error())
+	err = postForm(t, server, form, map[string]string{signer.FormKey: "other/a.png", HTTPHeaderContentType: "image/png"}, nil)
+	assert.True(t, strings.HasPrefix(err.Error(), signer.ErrPolicyCondition.Error()))
+	err = postForm(t, server, form, map[string]string{signer.FormKey: "ugc/a.html", HTTPHeaderContentType: "text/html"}, nil)
+	assert.True(t, strings.HasPrefix(err.Error(), signer.ErrPolicyCondition.Error()))
+	err = postForm(t, server, form, map[string]string{
+		signer.FormKey: "ugc/a.png", HTTPHeaderContentType: "image/png", "x-xiaomi-meta-owner": "mallory",
+	}, nil)
+	assert.True(t, strings.HasPrefix(err.Error(), signer.ErrFieldNotInPolicy.Error()))
+	err = postForm(t, server, form, map[string]string{
+		signer.FormKey: "ugc/a.png", HTTPHeaderContentType: "image/png", signer.FormSignature: "forged",
+	}, nil)
+	assert.Equal(t, signer.ErrSignatureMismatch.Error(), err.Error())
+
+	// bucket is bound to the form
+	other := *form
+	other.URL = client.buildRequestURL("other", "", "", false)
+	err = postForm(t, server, &other, valid, nil)
+	assert.True(t, strings.HasPrefix(err.Error(), signer.ErrPolicyCondition.Error()))
+
+	verifier.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	err = postForm(t, server, form, valid, nil)
+	assert.Equal(t, signer.ErrExpired.Error(), err.Error())
+}
+
+func TestGeneratePostPolicy_ExactFields(t *testing.T) {
+	conf, _ := NewClientConfiguration("cnbj0.fds.api.xiaomi.com")
+	client := New("ak", "sk", conf)
+	form, err := client.GeneratePostPolicy(&GeneratePostPolicyRequest{
+		BucketName:  "bucket",
+		Key:         "avatar.png",
+		ContentType: "image/png",
+		Expiration:  time.Now().Add(time.Minute),
+		Conditions:  []signer.PolicyCondition{signer.Equals("x-xiaomi-meta-owner", "alice")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "avatar.png", form.Fields[signer.FormKey])
+	assert.Equal(t, "image/png", form.Fields[HTTPHeaderContentType])
+
+	verifier := signer.NewVerifier(func(string) (string, error) { return "sk", nil })
+	fields := map[string]string{signer.FormBucket: "bucket", "x-xiaomi-meta-owner": "alice"}
+	for k, v := range form.Fields {
+		fields[k] = v
+	}
+	_, err = verifier.VerifyPostForm(fields, 1)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,156 @@
+package fds
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+	"github.com/XiaoMi/go-fds/fds/signer"
+)
+
+// GeneratePresignedURLRequest is input of GeneratePresignedURL
+type GeneratePresignedURLRequest struct {
+	CDN        bool
+	BucketName string
+	ObjectName string
+	Method     HTTPMethod
+	Expiration time.Time
+	// Metadata holds headers which must be sent with the URL, e.g. x-xiaomi-meta-*, it's optional
+	Metadata *ObjectMetadata
+
+	// ContentType and ContentMD5 are bound to the URL if they are set, requests
+	// with other values of these headers are rejected
+	ContentType string
+	ContentMD5  string
+
+	// SubResources are signed query parameters, e.g. uploadId and partNumber
+	SubResources map[string]string
+}
+
+// GeneratePresignedURL generates presigned url
+func (client *Client) GeneratePresignedURL(request *GeneratePresignedURLRequest) (*url.URL, error) {
+	baseURL := client.buildRequestURL(request.BucketName, request.ObjectName, "", request.CDN)
+
+	params := url.Values{}
+	if request.Method == HTTPHead {
+		params.Add("metadata", "")
+	}
+	for k, v := range request.SubResources {
+		params.Set(k, v)
+	}
+	baseURL.RawQuery = params.Encode()
+
+	header := http.Header{}
+	if request.Metadata != nil {
+		for k, v := range request.Metadata.metadata {
+			header.Set(k, v)
+		}
+	}
+	if request.ContentType != "" {
+		header.Set(HTTPHeaderContentType, request.ContentType)
+	}
+	if request.ContentMD5 != "" {
+		header.Set(HTTPHeaderContentMD5, request.ContentMD5)
+	}
+
+	return client.signer().Presign(string(request.Method), baseURL, header, client.AccessID, client.AccessSecret,
+		request.Expiration.Add(client.ClockOffset())), nil
+}
+
+// GeneratePresignedUploadPartURLRequest is input of GeneratePresignedUploadPartURL
+type GeneratePresignedUploadPartURLRequest struct {
+	CDN        bool
+	BucketName string
+	ObjectName string
+	UploadID   string
+	PartNumber int
+	Expiration time.Time
+	// ContentMD5 is bound to the URL if it's set
+	ContentMD5 string
+}
+
+// GeneratePresignedUploadPartURL generates a presigned URL for uploading a part of
+// multipart upload UploadID by PUT, e.g. directly from browsers
+func (client *Client) GeneratePresignedUploadPartURL(request *GeneratePresignedUploadPartURLRequest) (*url.URL, error) {
+	if request.UploadID == "" || request.PartNumber < 1 {
+		return nil, ErrorInvalidUploadPart
+	}
+
+	return client.GeneratePresignedURL(&GeneratePresignedURLRequest{
+		CDN:        request.CDN,
+		BucketName: request.BucketName,
+		ObjectName: request.ObjectName,
+		Method:     HTTPPut,
+		Expiration: request.Expiration,
+		ContentMD5: request.ContentMD5,
+		SubResources: map[string]string{
+			"uploadId":   request.UploadID,
+			"partNumber": strconv.Itoa(request.PartNumber),
+		},
+	})
+}
+
+// PresignedURL is information of a presigned URL
+type PresignedURL struct {
+	BucketName   string
+	ObjectName   string
+	AccessKeyID  string
+	Expiration   time.Time
+	Signature    string
+	SubResources map[string]string
+}
+
+// Expired tells whether the URL is expired at now
+func (p *PresignedURL) Expired(now time.Time) bool {
+	return now.After(p.Expiration)
+}
+
+// presignedQuery is query parameters of a presigned URL, names are the same as signer.Query*,
+// Expires is the last one so a malformed one doesn't stop decoding of others
+type presignedQuery struct {
+	AccessKeyID string    `param:"GalaxyAccessKeyId"`
+	Signature   string    `param:"Signature"`
+	Expires     time.Time `param:"Expires,unixmilli"`
+}
+
+// ParsePresignedURL parses a URL made by GeneratePresignedURL, sub-resources are
+// query parameters of signer.DefaultSubResources. The signature isn't verified,
+// use signer.Verifier for that.
+func ParsePresignedURL(u *url.URL) (*PresignedURL, error) {
+	query := u.Query()
+	var presigned presignedQuery
+	decodeErr := httpparser.DecodeQuery(query, &presigned)
+	if presigned.AccessKeyID == "" || presigned.Signature == "" {
+		return nil, ErrorNotPresignedURL
+	}
+	if decodeErr != nil || presigned.Expires.IsZero() {
+		return nil, signer.ErrMalformedExpires
+	}
+
+	result := &PresignedURL{
+		AccessKeyID:  presigned.AccessKeyID,
+		Signature:    presigned.Signature,
+		Expiration:   presigned.Expires,
+		SubResources: map[string]string{},
+	}
+
+	path := strings.TrimPrefix(u.Path, "/")
+	if path == "" {
+		return nil, ErrorNotPresignedURL
+	}
+	parts := strings.SplitN(path, "/", 2)
+	result.BucketName = parts[0]
+	if len(parts) == 2 {
+		result.ObjectName = parts[1]
+	}
+
+	for k := range query {
+		if signer.Default.IsSubResource(k) {
+			result.SubResources[k] = query.Get(k)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package fds
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPresignTest(t *testing.T) (*Client, *signer.Verifier) {
+	conf, _ := NewClientConfiguration("cnbj0.fds.api.xiaomi.com")
+	client := New("ak", "sk", conf)
+	verifier := signer.NewVerifier(func(accessKeyID string) (string, error) {
+		return "sk", nil
+	})
+	return client, verifier
+}
+
+func TestGeneratePresignedURL_HeaderConstraints(t *testing.T) {
+	client, verifier := newPresignTest(t)
+
+	// Metadata is optional
+	u, err := client.GeneratePresignedURL(&GeneratePresignedURLRequest{
+		BucketName:  "bucket",
+		ObjectName:  "video.mp4",
+		Method:      HTTPPut,
+		Expiration:  time.Now().Add(time.Minute),
+		ContentType: "video/mp4",
+		ContentMD5:  "1B2M2Y8AsgTpgAmY7PhCfg==",
+	})
+	assert.Nil(t, err)
+
+	header := http.Header{}
+	header.Set(HTTPHeaderContentType, "video/mp4")
+	header.Set(HTTPHeaderContentMD5, "1B2M2Y8AsgTpgAmY7PhCfg==")
+	_, err = verifier.VerifyURL(string(HTTPPut), u, header)
+	assert.Nil(t, err)
+
+	header.Set(HTTPHeaderContentType, "text/html")
+	_, err = verifier.VerifyURL(string(HTTPPut), u, header)
+	assert.Equal(t, signer.ErrSignatureMismatch, err)
+}
+
+func TestGeneratePresignedUploadPartURL(t *testing.T) {
+	client, verifier := newPresignTest(t)
+	expiration := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	u, err := client.GeneratePresignedUploadPartURL(&GeneratePresignedUploadPartURLRequest{
+		BucketName: "bucket",
+		ObjectName: "dir/video.mp4",
+		UploadID:   "upload-1",
+		PartNumber: 3,
+		Expiration: expiration,
+	})
+	assert.Nil(t, err)
+	_, err = verifier.VerifyURL(string(HTTPPut), u, nil)
+	assert.Nil(t, err)
+
+	// partNumber is signed
+	tampered := *u
+	query := tampered.Query()
+	query.Set("partNumber", "4")
+	tampered.RawQuery = query.Encode()
+	_, err = verifier.VerifyURL(string(HTTPPut), &tampered, nil)
+	assert.Equal(t, signer.ErrSignatureMismatch, err)
+
+	parsed, err := ParsePresignedURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "bucket", parsed.BucketName)
+	assert.Equal(t, "dir/video.mp4", parsed.ObjectName)
+	assert.Equal(t, "ak", parsed.AccessKeyID)
+	assert.True(t, expiration.Equal(parsed.Expiration))
+	assert.Equal(t, map[string]string{"uploadId": "upload-1", "partNumber": "3"}, parsed.SubResources)
+	assert.False(t, parsed.Expired(time.Now()))
+
+	_, err = client.GeneratePresignedUploadPartURL(&GeneratePresignedUploadPartURLRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		UploadID:   "upload-1",
+	})
+	assert.Equal(t, ErrorInvalidUploadPart, err)
+}
+
+func TestParsePresignedURL_Invalid(t *testing.T) {
+	u, _ := url.Parse("https://cnbj0.fds.api.xiaomi.com/bucket/object")
+	_, err := ParsePresignedURL(u)
+	assert.Equal(t, ErrorNotPresignedURL, err)
+
+	u, _ = url.Parse("https://cnbj0.fds.api.xiaomi.com/bucket/object?GalaxyAccessKeyId=ak&Expires=x&Signature=s")
+	_, err = ParsePresignedURL(u)
+	assert.Equal(t, signer.ErrMalformedExpires, err)
+}
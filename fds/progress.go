@@ -0,0 +1,250 @@
+package fds
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressEventType is type of ProgressEvent
+type ProgressEventType int
+
+// ProgressEventType const
+const (
+	ProgressStarted ProgressEventType = iota
+	ProgressBytesTransferred
+	ProgressPartCompleted
+	ProgressRetried
+	ProgressCompleted
+	ProgressFailed
+)
+
+// String makes ProgressEventType a string
+func (t ProgressEventType) String() string {
+	switch t {
+	case ProgressStarted:
+		return "started"
+	case ProgressBytesTransferred:
+		return "bytes-transferred"
+	case ProgressPartCompleted:
+		return "part-completed"
+	case ProgressRetried:
+		return "retried"
+	case ProgressCompleted:
+		return "completed"
+	case ProgressFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// ProgressEvent is sent to ProgressListener when progress of transfer changes
+type ProgressEvent struct {
+	Type       ProgressEventType
+	BucketName string
+	ObjectName string
+
+	// ConsumedBytes is bytes transferred till now
+	ConsumedBytes int64
+	// TotalBytes is bytes need to transfer, it is -1 if unknown
+	TotalBytes int64
+	// RwBytes is bytes transferred in this event
+	RwBytes int64
+	// PartNumber is set for part-completed and retried events
+	PartNumber int
+	// Rate is average bytes per second since started
+	Rate float64
+	// Elapsed is duration since started
+	Elapsed time.Duration
+	// Err is set for retried and failed events
+	Err error
+}
+
+// ProgressListener receives ProgressEvent, it's called synchronously in transfer,
+// so it should return quickly
+type ProgressListener interface {
+	ProgressChanged(event *ProgressEvent)
+}
+
+// ProgressListenerFunc is an adapter to use ordinary function as ProgressListener
+type ProgressListenerFunc func(event *ProgressEvent)
+
+// ProgressChanged calls f(event)
+func (f ProgressListenerFunc) ProgressChanged(event *ProgressEvent) {
+	f(event)
+}
+
+// ProgressTracker counts bytes of one transfer and sends events to ProgressListener.
+// All methods are safe for concurrent use and a nil ProgressTracker does nothing.
+type ProgressTracker struct {
+	mu sync.Mutex
+
+	listener   ProgressListener
+	bucketName string
+	objectName string
+	total      int64
+	consumed   int64
+	started    time.Time
+}
+
+// NewProgressTracker creates a ProgressTracker, it returns nil if listener is nil.
+// total is -1 if it is unknown.
+func NewProgressTracker(listener ProgressListener, bucketName, objectName string, total int64) *ProgressTracker {
+	if listener == nil {
+		return nil
+	}
+
+	return &ProgressTracker{
+		listener:   listener,
+		bucketName: bucketName,
+		objectName: objectName,
+		total:      total,
+		started:    time.Now(),
+	}
+}
+
+// SetTotal sets total bytes of transfer when it's known later
+func (t *ProgressTracker) SetTotal(total int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = total
+}
+
+// Started sends a started event
+func (t *ProgressTracker) Started() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started = time.Now()
+	t.publish(&ProgressEvent{Type: ProgressStarted})
+}
+
+// Transferred sends a bytes-transferred event of n bytes
+func (t *ProgressTracker) Transferred(n int64) {
+	if t == nil || n <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.consumed += n
+	t.publish(&ProgressEvent{Type: ProgressBytesTransferred, RwBytes: n})
+}
+
+// PartCompleted sends a part-completed event of partNumber with size bytes
+func (t *ProgressTracker) PartCompleted(partNumber int, size int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.publish(&ProgressEvent{Type: ProgressPartCompleted, PartNumber: partNumber, RwBytes: size})
+}
+
+// Retried sends a retried event of partNumber, rollback bytes transferred by the
+// failed attempt are subtracted from consumed bytes
+func (t *ProgressTracker) Retried(partNumber int, rollback int64, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.consumed -= rollback
+	t.publish(&ProgressEvent{Type: ProgressRetried, PartNumber: partNumber, RwBytes: -rollback, Err: err})
+}
+
+// Completed sends a completed event
+func (t *ProgressTracker) Completed() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.publish(&ProgressEvent{Type: ProgressCompleted})
+}
+
+// Failed sends a failed event
+func (t *ProgressTracker) Failed(err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.publish(&ProgressEvent{Type: ProgressFailed, Err: err})
+}
+
+func (t *ProgressTracker) publish(event *ProgressEvent) {
+	event.BucketName = t.bucketName
+	event.ObjectName = t.objectName
+	event.ConsumedBytes = t.consumed
+	event.TotalBytes = t.total
+	event.Elapsed = time.Since(t.started)
+	if seconds := event.Elapsed.Seconds(); seconds > 0 {
+		event.Rate = float64(t.consumed) / seconds
+	}
+	t.listener.ProgressChanged(event)
+}
+
+// newUploadProgressTracker creates a ProgressTracker with length of data as total
+func newUploadProgressTracker(listener ProgressListener, bucketName, objectName string, data io.Reader) *ProgressTracker {
+	total, ok := readerLength(data)
+	if !ok {
+		total = -1
+	}
+	return NewProgressTracker(listener, bucketName, objectName, total)
+}
+
+// progressReader reports bytes read from reader to tracker
+type progressReader struct {
+	reader  io.Reader
+	tracker *ProgressTracker
+
+	// completeOnEOF sends completed event when reader reaches EOF
+	completeOnEOF bool
+	finished      bool
+	// read is bytes reported since created or rolled back
+	read int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	r.tracker.Transferred(int64(n))
+
+	if err != nil && r.completeOnEOF && !r.finished {
+		r.finished = true
+		if err == io.EOF {
+			r.tracker.Completed()
+		} else {
+			r.tracker.Failed(err)
+		}
+	}
+	return n, err
+}
+
+// rollback sends a retried event to subtract bytes read, it's called before reader is sent again
+func (r *progressReader) rollback(err error) {
+	if r == nil {
+		return
+	}
+	r.tracker.Retried(0, r.read, err)
+	r.read = 0
+}
+
+func (r *progressReader) Close() error {
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package fds
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordListener struct {
+	mu     sync.Mutex
+	events []ProgressEvent
+}
+
+func (l *recordListener) ProgressChanged(event *ProgressEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, *event)
+}
+
+func (l *recordListener) types() []ProgressEventType {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var types []ProgressEventType
+	for _, e := range l.events {
+		if len(types) > 0 && types[len(types)-1] == e.Type {
+			continue
+		}
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+// newTestClient makes a client of a server served by handler, configure changes the configuration
+func newTestClient(t *testing.T, handler http.HandlerFunc, configure ...func(*ClientConfiguration)) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	conf, _ := NewClientConfiguration(strings.TrimPrefix(server.URL, "http://"))
+	conf.EnableHTTPS = false
+	for _, f := range configure {
+		f(conf)
+	}
+	return New("ak", "sk", conf)
+}
+
+func TestProgress_PutObject(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{}`))
+	})
+
+	listener := &recordListener{}
+	content := bytes.Repeat([]byte("x"), 100*1024)
+	_, err := client.PutObject(&PutObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Data:       bytes.NewReader(content),
+		Progress:   listener,
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []ProgressEventType{ProgressStarted, ProgressBytesTransferred, ProgressCompleted}, listener.types())
+	last := listener.events[len(listener.events)-1]
+	assert.Equal(t, int64(len(content)), last.ConsumedBytes)
+	assert.Equal(t, int64(len(content)), last.TotalBytes)
+	assert.Equal(t, "object", last.ObjectName)
+}
+
+func TestProgress_GetObject(t *testing.T) {
+	content := bytes.Repeat([]byte("y"), 64*1024)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(HTTPHeaderContentLength, strconv.Itoa(len(content)))
+		w.Write(content)
+	})
+
+	listener := &recordListener{}
+	reader, err := client.GetObject(&GetObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Progress:   listener,
+	})
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	reader.Close()
+	assert.Equal(t, content, data)
+
+	assert.Equal(t, []ProgressEventType{ProgressStarted, ProgressBytesTransferred, ProgressCompleted}, listener.types())
+	last := listener.events[len(listener.events)-1]
+	assert.Equal(t, int64(len(content)), last.ConsumedBytes)
+	assert.Equal(t, int64(len(content)), last.TotalBytes)
+
+	listener = &recordListener{}
+	_, err = client.GetObject(&GetObjectRequest{
+		BucketName: "bucket",
+		ObjectName: "missing",
+		Progress:   listener,
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []ProgressEventType{ProgressStarted, ProgressFailed}, listener.types())
+}
@@ -0,0 +1,248 @@
+package fds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+)
+
+// GetObjectRangesRequest is input of GetObjectRanges
+type GetObjectRangesRequest struct {
+	BucketName string
+	ObjectName string
+	Ranges     []httpparser.HTTPRange
+
+	// Concurrency limits requests sent at the same time if server doesn't respond
+	// multipart/byteranges, all ranges are requested at once if it's 0
+	Concurrency int
+}
+
+// ObjectRange is content of a requested range
+type ObjectRange struct {
+	// ContentRange is the resolved range, Size is -1 if server doesn't tell it
+	ContentRange httpparser.ContentRange
+	Body         io.ReadCloser
+}
+
+type getObjectRangeOption struct {
+	Range string `param:"-" header:"Range"`
+}
+
+// GetObjectRanges gets ranges of object in one multipart/byteranges response, or by parallel
+// single range requests if server rejects multiple ranges or doesn't answer multipart/byteranges. An ObjectRange
+// is returned for each of Ranges in the same order, bodies must be closed by caller.
+// Parts of multipart/byteranges response are buffered in memory.
+func (client *Client) GetObjectRanges(request *GetObjectRangesRequest) ([]*ObjectRange, error) {
+	return client.GetObjectRangesWithContext(context.Background(), request)
+}
+
+// GetObjectRangesWithContext gets ranges of object with context controlling
+func (client *Client) GetObjectRangesWithContext(ctx context.Context, request *GetObjectRangesRequest) ([]*ObjectRange, error) {
+	if len(request.Ranges) == 0 {
+		return nil, ErrorEmptyRanges
+	}
+	if len(request.Ranges) == 1 {
+		return client.getObjectRangesParallel(ctx, request)
+	}
+
+	resp, err := client.getObjectRange(ctx, request, httpparser.FormatRange(request.Ranges...))
+	if isMultipleRangesRejected(err) {
+		return client.getObjectRangesParallel(ctx, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get(HTTPHeaderContentType))
+	if resp.StatusCode != http.StatusPartialContent || mediaType != "multipart/byteranges" {
+		// ranges are coalesced or ignored by server
+		return client.getObjectRangesParallel(ctx, request)
+	}
+
+	parts, size, err := readByteranges(resp.Body, params["boundary"])
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return client.getObjectRangesParallel(ctx, request)
+	}
+
+	result := make([]*ObjectRange, len(request.Ranges))
+	for i, r := range request.Ranges {
+		resolved, err := r.Resolve(size)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i], err = sliceByteranges(parts, resolved, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// isMultipleRangesRejected tells whether err is a response of server which doesn't accept
+// multiple ranges in a request, each range is then requested alone to get its own result
+func isMultipleRangesRejected(err error) bool {
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	code := serverErr.Code()
+	return code == http.StatusBadRequest || code == http.StatusRequestedRangeNotSatisfiable
+}
+
+func (client *Client) getObjectRange(ctx context.Context, request *GetObjectRangesRequest, r string) (*http.Response, error) {
+	req := &clientRequest{
+		BucketName:         request.BucketName,
+		ObjectName:         request.ObjectName,
+		QueryHeaderOptions: getObjectRangeOption{Range: r},
+		Method:             HTTPGet,
+		Operation:          "GetObjectRanges",
+		CDN:                true,
+	}
+	return client.do(ctx, req)
+}
+
+// bytesPart is a part of multipart/byteranges response
+type bytesPart struct {
+	r    httpparser.HTTPRange
+	data []byte
+}
+
+// readByteranges reads all parts of a multipart/byteranges body, size is -1 if it's unknown
+func readByteranges(body io.Reader, boundary string) ([]bytesPart, int64, error) {
+	var parts []bytesPart
+	size := int64(-1)
+
+	reader := multipart.NewReader(body, boundary)
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		cr, err := httpparser.ParseContentRange(p.Header.Get(HTTPHeaderContentRange))
+		if err != nil {
+			return nil, 0, err
+		}
+		if cr.Size >= 0 {
+			size = cr.Size
+		}
+
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		if int64(len(data)) != cr.Range().Length() {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		parts = append(parts, bytesPart{r: cr.Range(), data: data})
+	}
+	return parts, size, nil
+}
+
+// sliceByteranges finds the part containing resolved range r
+func sliceByteranges(parts []bytesPart, r httpparser.HTTPRange, size int64) (*ObjectRange, error) {
+	for _, p := range parts {
+		if p.r.Start <= r.Start && p.r.End >= r.End {
+			data := p.data[r.Start-p.r.Start : r.End-p.r.Start+1]
+			return &ObjectRange{
+				ContentRange: httpparser.ContentRange{Start: r.Start, End: r.End, Size: size},
+				Body:         ioutil.NopCloser(bytes.NewReader(data)),
+			}, nil
+		}
+	}
+	// server drops unsatisfiable ranges
+	return nil, httpparser.ErrRangeNotSatisfiable
+}
+
+// getObjectRangesParallel requests each range of request concurrently
+func (client *Client) getObjectRangesParallel(ctx context.Context, request *GetObjectRangesRequest) ([]*ObjectRange, error) {
+	concurrency := request.Concurrency
+	if concurrency <= 0 {
+		concurrency = len(request.Ranges)
+	}
+
+	result := make([]*ObjectRange, len(request.Ranges))
+	errs := make([]error, len(request.Ranges))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, r := range request.Ranges {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, r httpparser.HTTPRange) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result[i], errs[i] = client.getSingleRange(ctx, request, r)
+		}(i, r)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			for _, r := range result {
+				if r != nil {
+					r.Body.Close()
+				}
+			}
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// getSingleRange requests range r, the whole object is cut if server ignores Range header
+func (client *Client) getSingleRange(ctx context.Context, request *GetObjectRangesRequest, r httpparser.HTTPRange) (*ObjectRange, error) {
+	resp, err := client.getObjectRange(ctx, request, httpparser.FormatRange(r))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusPartialContent {
+		cr, err := httpparser.ParseContentRange(resp.Header.Get(HTTPHeaderContentRange))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return &ObjectRange{ContentRange: cr, Body: resp.Body}, nil
+	}
+
+	if resp.ContentLength < 0 || strings.HasPrefix(resp.Header.Get(HTTPHeaderContentType), "multipart/") {
+		resp.Body.Close()
+		return nil, httpparser.ErrContentRangeFormat
+	}
+	resolved, err := r.Resolve(resp.ContentLength)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, resp.Body, resolved.Start); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return &ObjectRange{
+		ContentRange: httpparser.ContentRange{Start: resolved.Start, End: resolved.End, Size: resp.ContentLength},
+		Body: struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(resp.Body, resolved.Length()), resp.Body},
+	}, nil
+}
@@ -0,0 +1,131 @@
+package fds
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/XiaoMi/go-fds/fds/httpparser"
+	"github.com/stretchr/testify/assert"
+)
+
+// rangeHandler serves content with multipart/byteranges if multipart is true,
+// otherwise only the first requested range is served
+func rangeHandler(t *testing.T, content []byte, multipartEnabled bool, requests *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		ranges, err := httpparser.Range(r.Header.Get(HTTPHeaderRange))
+		assert.Nil(t, err)
+		ranges, err = httpparser.ResolveRanges(ranges, int64(len(content)))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		size := int64(len(content))
+
+		if len(ranges) == 1 || !multipartEnabled {
+			cr := httpparser.ContentRange{Start: ranges[0].Start, End: ranges[0].End, Size: size}
+			w.Header().Set(HTTPHeaderContentRange, cr.String())
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[cr.Start : cr.End+1])
+			return
+		}
+
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for _, rr := range ranges {
+			cr := httpparser.ContentRange{Start: rr.Start, End: rr.End, Size: size}
+			pw, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Range": {cr.String()}})
+			pw.Write(content[cr.Start : cr.End+1])
+		}
+		mw.Close()
+		w.Header().Set(HTTPHeaderContentType, "multipart/byteranges; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(buf.Bytes())
+	}
+}
+
+func readRanges(t *testing.T, ranges []*ObjectRange) [][]byte {
+	var result [][]byte
+	for _, r := range ranges {
+		data, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		r.Body.Close()
+		result = append(result, data)
+	}
+	return result
+}
+
+func TestGetObjectRanges(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	request := &GetObjectRangesRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Ranges:     []httpparser.HTTPRange{{Start: 2, End: 4}, {Start: -3, End: -1}, {Start: 10, End: -1}},
+	}
+	expected := [][]byte{[]byte("234"), []byte("hij"), []byte("abcdefghij")}
+
+	for _, multipartEnabled := range []bool{true, false} {
+		var requests int32
+		client := newTestClient(t, rangeHandler(t, content, multipartEnabled, &requests))
+
+		ranges, err := client.GetObjectRanges(request)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, readRanges(t, ranges))
+		assert.Equal(t, httpparser.ContentRange{Start: 17, End: 19, Size: 20}, ranges[1].ContentRange)
+		if multipartEnabled {
+			assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+		} else {
+			// the first request falls back to a request per range
+			assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+		}
+	}
+
+	var requests int32
+	client := newTestClient(t, rangeHandler(t, content, true, &requests))
+	_, err := client.GetObjectRanges(&GetObjectRangesRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Equal(t, ErrorEmptyRanges, err)
+
+	_, err = client.GetObjectRanges(&GetObjectRangesRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Ranges:     []httpparser.HTTPRange{{Start: 2, End: 4}, {Start: 100, End: -1}},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestGetObjectRanges_Fallback(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	request := &GetObjectRangesRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Ranges:     []httpparser.HTTPRange{{Start: 2, End: 4}, {Start: -3, End: -1}},
+	}
+
+	// server rejects or ignores multiple ranges in a request
+	for _, status := range []int{http.StatusBadRequest, http.StatusRequestedRangeNotSatisfiable, http.StatusOK} {
+		var requests int32
+		handler := rangeHandler(t, content, true, &requests)
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get(HTTPHeaderRange), ",") {
+				handler(w, r)
+				return
+			}
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				w.Write(content)
+			}
+		})
+
+		ranges, err := client.GetObjectRanges(request)
+		assert.Nil(t, err, status)
+		assert.Equal(t, [][]byte{[]byte("234"), []byte("hij")}, readRanges(t, ranges))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	}
+}
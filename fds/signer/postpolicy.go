@@ -0,0 +1,284 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fields of POST form uploads
+const (
+	FormPolicy      = "policy"
+	FormSignature   = QuerySignature
+	FormAccessKeyID = QueryAccessKeyID
+	FormKey         = "key"
+	FormBucket      = "bucket"
+	FormFile        = "file"
+)
+
+// Operators of PolicyCondition
+const (
+	PolicyEquals             = "eq"
+	PolicyStartsWith         = "starts-with"
+	PolicyContentLengthRange = "content-length-range"
+)
+
+// Errors of POST policies
+var (
+	ErrMalformedPolicy   = errors.New("malformed post policy")
+	ErrPolicyCondition   = errors.New("post policy condition failed")
+	ErrFieldNotInPolicy  = errors.New("form field is not allowed by post policy")
+	ErrMissingFormFile   = errors.New("file of form is missing")
+	ErrPolicyContentSize = errors.New("content length is out of post policy range")
+)
+
+// PolicyCondition is a condition of PostPolicy. Field is a form field name without $,
+// Min and Max are used by PolicyContentLengthRange only
+type PolicyCondition struct {
+	Op    string
+	Field string
+	Value string
+	Min   int64
+	Max   int64
+}
+
+// Equals is a condition that field must be value
+func Equals(field, value string) PolicyCondition {
+	return PolicyCondition{Op: PolicyEquals, Field: field, Value: value}
+}
+
+// StartsWith is a condition that field must start with prefix, any value is allowed if prefix is empty
+func StartsWith(field, prefix string) PolicyCondition {
+	return PolicyCondition{Op: PolicyStartsWith, Field: field, Value: prefix}
+}
+
+// ContentLengthRange is a condition that size of uploaded file is in [min, max]
+func ContentLengthRange(min, max int64) PolicyCondition {
+	return PolicyCondition{Op: PolicyContentLengthRange, Min: min, Max: max}
+}
+
+// MarshalJSON encodes c as {"field":"value"} or [op, "$field", value]
+func (c PolicyCondition) MarshalJSON() ([]byte, error) {
+	switch c.Op {
+	case PolicyEquals:
+		return json.Marshal(map[string]string{c.Field: c.Value})
+	case PolicyStartsWith:
+		return json.Marshal([]string{c.Op, "$" + c.Field, c.Value})
+	case PolicyContentLengthRange:
+		return json.Marshal([]interface{}{c.Op, c.Min, c.Max})
+	}
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrMalformedPolicy, c.Op)
+}
+
+// UnmarshalJSON decodes c from its object or array form
+func (c *PolicyCondition) UnmarshalJSON(data []byte) error {
+	var object map[string]string
+	if err := json.Unmarshal(data, &object); err == nil {
+		if len(object) != 1 {
+			return ErrMalformedPolicy
+		}
+		for k, v := range object {
+			*c = Equals(strings.TrimPrefix(k, "$"), v)
+		}
+		return nil
+	}
+
+	var array []json.RawMessage
+	if err := json.Unmarshal(data, &array); err != nil || len(array) != 3 {
+		return ErrMalformedPolicy
+	}
+	var op string
+	if err := json.Unmarshal(array[0], &op); err != nil {
+		return ErrMalformedPolicy
+	}
+
+	switch strings.ToLower(op) {
+	case PolicyContentLengthRange:
+		*c = PolicyCondition{Op: PolicyContentLengthRange}
+		if json.Unmarshal(array[1], &c.Min) != nil || json.Unmarshal(array[2], &c.Max) != nil {
+			return ErrMalformedPolicy
+		}
+	case PolicyEquals, PolicyStartsWith:
+		var field, value string
+		if json.Unmarshal(array[1], &field) != nil || json.Unmarshal(array[2], &value) != nil ||
+			!strings.HasPrefix(field, "$") {
+			return ErrMalformedPolicy
+		}
+		*c = PolicyCondition{Op: strings.ToLower(op), Field: strings.TrimPrefix(field, "$"), Value: value}
+	default:
+		return ErrMalformedPolicy
+	}
+	return nil
+}
+
+// check tells whether field values satisfy c, field names are case-insensitive
+func (c PolicyCondition) check(fields map[string]string, size int64) bool {
+	switch c.Op {
+	case PolicyContentLengthRange:
+		return size >= c.Min && size <= c.Max
+	case PolicyEquals:
+		return fields[strings.ToLower(c.Field)] == c.Value
+	case PolicyStartsWith:
+		return strings.HasPrefix(fields[strings.ToLower(c.Field)], c.Value)
+	}
+	return false
+}
+
+// PostPolicy is the policy document of a POST form upload
+type PostPolicy struct {
+	Expiration time.Time         `json:"expiration"`
+	Conditions []PolicyCondition `json:"conditions"`
+}
+
+// Encode returns base64 encoded JSON of policy, which is the policy field of form
+func (p *PostPolicy) Encode() (string, error) {
+	data, err := json.Marshal(struct {
+		Expiration string            `json:"expiration"`
+		Conditions []PolicyCondition `json:"conditions"`
+	}{p.Expiration.UTC().Format("2006-01-02T15:04:05.000Z"), p.Conditions})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodePostPolicy decodes policy field of form
+func DecodePostPolicy(encoded string) (*PostPolicy, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrMalformedPolicy
+	}
+	p := &PostPolicy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, ErrMalformedPolicy
+	}
+	return p, nil
+}
+
+// PolicySignature returns base64 encoded HMAC-SHA1 of encoded policy with secret
+func PolicySignature(secret, encodedPolicy string) string {
+	h := hmac.New(sha1.New, []byte(secret))
+	h.Write([]byte(encodedPolicy))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SignPostPolicy returns fields of form for p: policy, GalaxyAccessKeyId and Signature
+func SignPostPolicy(p *PostPolicy, accessKeyID, secret string) (map[string]string, error) {
+	encoded, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		FormPolicy:      encoded,
+		FormAccessKeyID: accessKeyID,
+		FormSignature:   PolicySignature(secret, encoded),
+	}, nil
+}
+
+// isPolicyExempt tells whether a form field needn't be covered by conditions
+func isPolicyExempt(field string) bool {
+	switch field {
+	case FormPolicy, strings.ToLower(FormSignature), strings.ToLower(FormAccessKeyID), FormFile:
+		return true
+	}
+	return strings.HasPrefix(field, "x-ignore-")
+}
+
+// VerifyPostForm checks signature and expiration of policy in fields, and that fields and
+// size of uploaded file satisfy all conditions. Every field except policy, signature,
+// access key, file and x-ignore-* must be covered by a condition. The bucket field should
+// be set from the request URL by caller.
+func (v *Verifier) VerifyPostForm(fields map[string]string, size int64) (*PostPolicy, error) {
+	lower := make(map[string]string, len(fields))
+	for k, value := range fields {
+		lower[strings.ToLower(k)] = value
+	}
+
+	encoded, signature := lower[FormPolicy], lower[strings.ToLower(FormSignature)]
+	accessKeyID := lower[strings.ToLower(FormAccessKeyID)]
+	if encoded == "" || signature == "" || accessKeyID == "" {
+		return nil, ErrMissingSignature
+	}
+
+	secret, err := v.Lookup(accessKeyID)
+	if err != nil {
+		return nil, err
+	}
+	expected := PolicySignature(secret, encoded)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return nil, ErrSignatureMismatch
+	}
+
+	p, err := DecodePostPolicy(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if v.now().After(p.Expiration) {
+		return nil, ErrExpired
+	}
+
+	covered := map[string]bool{}
+	for _, c := range p.Conditions {
+		if !c.check(lower, size) {
+			if c.Op == PolicyContentLengthRange {
+				return nil, ErrPolicyContentSize
+			}
+			return nil, fmt.Errorf("%w: %s %s", ErrPolicyCondition, c.Op, c.Field)
+		}
+		covered[strings.ToLower(c.Field)] = true
+	}
+	for k := range lower {
+		if !covered[k] && !isPolicyExempt(k) {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotInPolicy, k)
+		}
+	}
+	return p, nil
+}
+
+// VerifyPostRequest parses a multipart/form-data POST upload and verifies it by VerifyPostForm,
+// bucket is the first segment of URL path. Fields must come before file as browsers send them.
+func (v *Verifier) VerifyPostRequest(req *http.Request) (*PostPolicy, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, ErrMissingFormFile
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() == FormFile {
+			size, err := io.Copy(ioutil.Discard, part)
+			if err != nil {
+				return nil, err
+			}
+			// bucket of URL can't be overridden by form
+			delete(fields, FormBucket)
+			if bucket := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2)[0]; bucket != "" {
+				fields[FormBucket] = bucket
+			}
+			return v.VerifyPostForm(fields, size)
+		}
+
+		value, err := ioutil.ReadAll(io.LimitReader(part, 64*1024))
+		if err != nil {
+			return nil, err
+		}
+		fields[part.FormName()] = string(value)
+	}
+}
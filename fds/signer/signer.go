@@ -0,0 +1,210 @@
+// Package signer implements Galaxy-V2 signature of FDS, it signs requests and
+// pre-signed URLs, and verifies them for gateways or mock services.
+package signer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheme is the authorization scheme of Galaxy-V2 signature
+const Scheme = "Galaxy-V2"
+
+// Names of headers and query parameters involved in signature
+const (
+	XiaomiPrefix = "x-xiaomi-"
+
+	HeaderAuthorization = "Authorization"
+	HeaderDate          = "Date"
+	HeaderContentMD5    = "Content-MD5"
+	HeaderContentType   = "Content-Type"
+
+	QueryAccessKeyID = "GalaxyAccessKeyId"
+	QueryExpires     = "Expires"
+	QuerySignature   = "Signature"
+)
+
+// DefaultSubResources are query parameters which are part of the signed resource
+var DefaultSubResources = []string{
+	"acl",
+	"quota",
+	"uploads",
+	"partNumber",
+	"uploadId",
+	"storageAccessToken",
+	"metadata",
+}
+
+// Signer computes Galaxy-V2 signatures, the zero value is not usable, use New instead
+type Signer struct {
+	subResources map[string]struct{}
+}
+
+// New makes a Signer of DefaultSubResources and extra sub-resources
+func New(subResources ...string) *Signer {
+	s := &Signer{subResources: map[string]struct{}{}}
+	s.AddSubResources(DefaultSubResources...)
+	s.AddSubResources(subResources...)
+	return s
+}
+
+// Default is the Signer of DefaultSubResources
+var Default = New()
+
+// AddSubResources adds sub-resources, it should be called before Signer is used concurrently
+func (s *Signer) AddSubResources(names ...string) {
+	for _, name := range names {
+		s.subResources[name] = struct{}{}
+	}
+}
+
+// IsSubResource tells whether query parameter name is a sub-resource
+func (s *Signer) IsSubResource(name string) bool {
+	_, ok := s.subResources[name]
+	return ok
+}
+
+// StringToSign returns the canonical string signed for a request, Expires in
+// query of u replaces Date header for pre-signed URLs
+func (s *Signer) StringToSign(method string, u *url.URL, header http.Header) string {
+	query := u.Query()
+
+	date := query.Get(QueryExpires)
+	if date == "" {
+		date = header.Get(HeaderDate)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(method)
+	buf.WriteString("\n")
+	buf.WriteString(header.Get(HeaderContentMD5))
+	buf.WriteString("\n")
+	buf.WriteString(header.Get(HeaderContentType))
+	buf.WriteString("\n")
+	buf.WriteString(date)
+	buf.WriteString("\n")
+	s.writeHeaders(&buf, header)
+	s.writeResource(&buf, u.Path, query)
+	return buf.String()
+}
+
+// writeHeaders writes sorted x-xiaomi- headers
+func (s *Signer) writeHeaders(buf *bytes.Buffer, header http.Header) {
+	var keys []string
+	values := map[string]string{}
+	for k, v := range header {
+		key := strings.ToLower(k)
+		if !strings.HasPrefix(key, XiaomiPrefix) {
+			continue
+		}
+		values[key] = strings.Join(v, ",")
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		buf.WriteString(k)
+		buf.WriteString(":")
+		buf.WriteString(values[k])
+		buf.WriteString("\n")
+	}
+}
+
+// writeResource writes path and sorted sub-resources
+func (s *Signer) writeResource(buf *bytes.Buffer, path string, query url.Values) {
+	buf.WriteString(path)
+
+	var keys []string
+	for k := range query {
+		if s.IsSubResource(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if i == 0 {
+			buf.WriteString("?")
+		} else {
+			buf.WriteString("&")
+		}
+		buf.WriteString(k)
+		if v := query.Get(k); v != "" {
+			buf.WriteString("=")
+			buf.WriteString(v)
+		}
+	}
+}
+
+// Signature returns base64 encoded HMAC-SHA1 of StringToSign with secret
+func (s *Signer) Signature(secret, method string, u *url.URL, header http.Header) string {
+	h := hmac.New(sha1.New, []byte(secret))
+	h.Write([]byte(s.StringToSign(method, u, header)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Sign sets Authorization header of req, Date header is set to now if it's empty
+func (s *Signer) Sign(req *http.Request, accessKeyID, secret string) {
+	if req.Header.Get(HeaderDate) == "" {
+		req.Header.Set(HeaderDate, time.Now().UTC().Format(http.TimeFormat))
+	}
+	signature := s.Signature(secret, req.Method, req.URL, req.Header)
+	req.Header.Set(HeaderAuthorization, Authorization(accessKeyID, signature))
+}
+
+// Presign returns a copy of u which is signed till expires, header holds headers
+// which must be sent with the URL, e.g. Content-Type or x-xiaomi-meta-*
+func (s *Signer) Presign(method string, u *url.URL, header http.Header, accessKeyID, secret string,
+	expires time.Time) *url.URL {
+	signed := *u
+	query := u.Query()
+	query.Del(QuerySignature)
+	query.Set(QueryAccessKeyID, accessKeyID)
+	query.Set(QueryExpires, FormatExpires(expires))
+	signed.RawQuery = query.Encode()
+
+	signature := s.Signature(secret, method, &signed, header)
+	// Signature is kept at the end
+	signed.RawQuery += "&" + QuerySignature + "=" + url.QueryEscape(signature)
+	return &signed
+}
+
+// Authorization formats value of Authorization header
+func Authorization(accessKeyID, signature string) string {
+	return Scheme + " " + accessKeyID + ":" + signature
+}
+
+// ParseAuthorization parses value of Authorization header
+func ParseAuthorization(auth string) (accessKeyID, signature string, err error) {
+	if !strings.HasPrefix(auth, Scheme+" ") {
+		return "", "", ErrMalformedAuthorization
+	}
+	credential := strings.TrimPrefix(auth, Scheme+" ")
+	i := strings.LastIndex(credential, ":")
+	if i <= 0 || i == len(credential)-1 {
+		return "", "", ErrMalformedAuthorization
+	}
+	return credential[:i], credential[i+1:], nil
+}
+
+// FormatExpires formats expiration of pre-signed URL in milliseconds
+func FormatExpires(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// ParseExpires parses expiration of pre-signed URL in milliseconds
+func ParseExpires(s string) (time.Time, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrMalformedExpires
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
@@ -0,0 +1,108 @@
+package signer_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func lookup(accessKeyID string) (string, error) {
+	if accessKeyID != "ak" {
+		return "", signer.ErrUnknownAccessKey
+	}
+	return "sk", nil
+}
+
+func TestStringToSign(t *testing.T) {
+	u, _ := url.Parse("http://cnbj0.fds.api.xiaomi.com/bucket/object?uploadId=123&partNumber=1&foo=bar")
+	header := http.Header{}
+	header.Set(signer.HeaderDate, "Mon, 19 Oct 2026 10:00:00 GMT")
+	header.Set(signer.HeaderContentType, "text/plain")
+	header.Set("X-Xiaomi-Meta-B", "2")
+	header.Set("X-Xiaomi-Meta-A", "1")
+
+	expected := "PUT\n\ntext/plain\nMon, 19 Oct 2026 10:00:00 GMT\n" +
+		"x-xiaomi-meta-a:1\nx-xiaomi-meta-b:2\n" +
+		"/bucket/object?partNumber=1&uploadId=123"
+	assert.Equal(t, expected, signer.Default.StringToSign("PUT", u, header))
+
+	// Expires replaces Date
+	u, _ = url.Parse("http://cnbj0.fds.api.xiaomi.com/bucket/object?metadata&Expires=1000&GalaxyAccessKeyId=ak")
+	assert.Equal(t, "HEAD\n\n\n1000\n/bucket/object?metadata", signer.Default.StringToSign("HEAD", u, http.Header{}))
+}
+
+func TestSubResources(t *testing.T) {
+	u, _ := url.Parse("http://cnbj0.fds.api.xiaomi.com/bucket?lifecycle")
+	assert.Equal(t, "GET\n\n\n\n/bucket", signer.Default.StringToSign("GET", u, http.Header{}))
+
+	s := signer.New("lifecycle")
+	assert.True(t, s.IsSubResource("lifecycle"))
+	assert.True(t, s.IsSubResource("acl"))
+	assert.Equal(t, "GET\n\n\n\n/bucket?lifecycle", s.StringToSign("GET", u, http.Header{}))
+}
+
+func TestVerify(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://cnbj0.fds.api.xiaomi.com/bucket/object?acl", nil)
+	req.Header.Set("X-Xiaomi-Meta-A", "1")
+	signer.Default.Sign(req, "ak", "sk")
+	assert.NotEmpty(t, req.Header.Get(signer.HeaderDate))
+
+	verifier := signer.NewVerifier(lookup)
+	verifier.MaxSkew = time.Minute
+	accessKeyID, err := verifier.Verify(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", accessKeyID)
+
+	// signed headers are tampered
+	req.Header.Set("X-Xiaomi-Meta-A", "2")
+	_, err = verifier.Verify(req)
+	assert.Equal(t, signer.ErrSignatureMismatch, err)
+	req.Header.Set("X-Xiaomi-Meta-A", "1")
+
+	verifier.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, err = verifier.Verify(req)
+	assert.Equal(t, signer.ErrRequestTimeTooSkewed, err)
+	verifier.Now = nil
+
+	req.Header.Set(signer.HeaderAuthorization, signer.Authorization("other", "signature"))
+	_, err = verifier.Verify(req)
+	assert.Equal(t, signer.ErrUnknownAccessKey, err)
+
+	req.Header.Del(signer.HeaderAuthorization)
+	_, err = verifier.Verify(req)
+	assert.Equal(t, signer.ErrMissingSignature, err)
+
+	req.Header.Set(signer.HeaderAuthorization, "Basic abc")
+	_, err = verifier.Verify(req)
+	assert.Equal(t, signer.ErrMalformedAuthorization, err)
+}
+
+func TestVerifyURL(t *testing.T) {
+	u, _ := url.Parse("http://cnbj0.fds.api.xiaomi.com/bucket/object")
+	header := http.Header{}
+	header.Set(signer.HeaderContentType, "image/png")
+	presigned := signer.Default.Presign(http.MethodPut, u, header, "ak", "sk", time.Now().Add(time.Minute))
+	assert.Equal(t, "", u.RawQuery)
+
+	verifier := signer.NewVerifier(lookup)
+	req, _ := http.NewRequest(http.MethodPut, presigned.String(), nil)
+	req.Header.Set(signer.HeaderContentType, "image/png")
+	accessKeyID, err := verifier.Verify(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", accessKeyID)
+
+	// Content-Type is bound to URL
+	_, err = verifier.VerifyURL(http.MethodPut, presigned, http.Header{})
+	assert.Equal(t, signer.ErrSignatureMismatch, err)
+
+	_, err = verifier.VerifyURL(http.MethodGet, presigned, header)
+	assert.Equal(t, signer.ErrSignatureMismatch, err)
+
+	verifier.Now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = verifier.VerifyURL(http.MethodPut, presigned, header)
+	assert.Equal(t, signer.ErrExpired, err)
+}
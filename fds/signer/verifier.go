@@ -0,0 +1,135 @@
+package signer
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Errors of verification
+var (
+	ErrMissingSignature       = errors.New("signature is missing")
+	ErrMalformedAuthorization = errors.New("malformed authorization")
+	ErrMalformedExpires       = errors.New("malformed expires")
+	ErrMalformedDate          = errors.New("malformed date")
+	ErrUnknownAccessKey       = errors.New("unknown access key")
+	ErrSignatureMismatch      = errors.New("signature mismatch")
+	ErrExpired                = errors.New("signature expired")
+	ErrRequestTimeTooSkewed   = errors.New("request time too skewed")
+)
+
+// SecretLookup returns secret of accessKeyID, ErrUnknownAccessKey should be
+// returned if there is no such key
+type SecretLookup func(accessKeyID string) (secret string, err error)
+
+// Verifier checks signatures of incoming requests and pre-signed URLs
+type Verifier struct {
+	// Signer computes expected signatures, Default is used if it's nil
+	Signer *Signer
+	// Lookup finds secret of access key
+	Lookup SecretLookup
+	// MaxSkew limits difference between Date header and now, 0 means no limit
+	MaxSkew time.Duration
+	// Now returns current time, time.Now is used if it's nil
+	Now func() time.Time
+}
+
+// NewVerifier makes a Verifier of Default signer
+func NewVerifier(lookup SecretLookup) *Verifier {
+	return &Verifier{Lookup: lookup}
+}
+
+func (v *Verifier) signer() *Signer {
+	if v.Signer == nil {
+		return Default
+	}
+	return v.Signer
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now == nil {
+		return time.Now()
+	}
+	return v.Now()
+}
+
+// Verify checks Authorization header of req, or signature in query if req is
+// made from a pre-signed URL, and returns access key of the signature
+func (v *Verifier) Verify(req *http.Request) (accessKeyID string, err error) {
+	if req.Header.Get(HeaderAuthorization) == "" && req.URL.Query().Get(QuerySignature) != "" {
+		return v.VerifyURL(req.Method, req.URL, req.Header)
+	}
+
+	accessKeyID, signature, err := ParseAuthorization(req.Header.Get(HeaderAuthorization))
+	if err != nil {
+		if req.Header.Get(HeaderAuthorization) == "" {
+			err = ErrMissingSignature
+		}
+		return "", err
+	}
+
+	if v.MaxSkew > 0 {
+		date, err := parseDate(req.Header.Get(HeaderDate))
+		if err != nil {
+			return "", err
+		}
+		skew := v.now().Sub(date)
+		if skew > v.MaxSkew || skew < -v.MaxSkew {
+			return "", ErrRequestTimeTooSkewed
+		}
+	}
+
+	return accessKeyID, v.check(accessKeyID, signature, req.Method, req.URL, req.Header)
+}
+
+// VerifyURL checks a pre-signed URL used with method and header
+func (v *Verifier) VerifyURL(method string, u *url.URL, header http.Header) (accessKeyID string, err error) {
+	query := u.Query()
+	accessKeyID = query.Get(QueryAccessKeyID)
+	signature := query.Get(QuerySignature)
+	if accessKeyID == "" || signature == "" {
+		return "", ErrMissingSignature
+	}
+
+	expires, err := ParseExpires(query.Get(QueryExpires))
+	if err != nil {
+		return "", err
+	}
+	if v.now().After(expires) {
+		return "", ErrExpired
+	}
+
+	unsigned := *u
+	query.Del(QuerySignature)
+	unsigned.RawQuery = query.Encode()
+	if header == nil {
+		header = http.Header{}
+	}
+	return accessKeyID, v.check(accessKeyID, signature, method, &unsigned, header)
+}
+
+func (v *Verifier) check(accessKeyID, signature, method string, u *url.URL, header http.Header) error {
+	secret, err := v.Lookup(accessKeyID)
+	if err != nil {
+		return err
+	}
+
+	expected := v.signer().Signature(secret, method, u, header)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// parseDate accepts formats of http.ParseTime and time.RFC1123 with any zone
+func parseDate(s string) (time.Time, error) {
+	if t, err := http.ParseTime(s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC1123, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, ErrMalformedDate
+}
@@ -0,0 +1,40 @@
+package fds
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/XiaoMi/go-fds/fds/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner_ClientRequestVerifies(t *testing.T) {
+	verifier := signer.NewVerifier(func(accessKeyID string) (string, error) {
+		return "sk", nil
+	})
+	verifier.MaxSkew = time.Minute
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := verifier.Verify(r); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	_, err := client.GetObjectMetadata("bucket", "object")
+	assert.Nil(t, err)
+
+	presigned, err := client.GeneratePresignedURL(&GeneratePresignedURLRequest{
+		BucketName: "bucket",
+		ObjectName: "object",
+		Method:     HTTPHead,
+		Expiration: time.Now().Add(time.Minute),
+		Metadata:   NewObjectMetadata(),
+	})
+	assert.Nil(t, err)
+	accessKeyID, err := verifier.VerifyURL(string(HTTPHead), presigned, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", accessKeyID)
+}
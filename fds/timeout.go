@@ -0,0 +1,152 @@
+package fds
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RequestTimeout overrides timeouts of ClientConfiguration for requests made with a context
+// returned by WithRequestTimeout, zero fields are not overridden
+type RequestTimeout struct {
+	// Total limits the whole request including reading response body
+	Total time.Duration
+	// ReadWrite is the longest time a request or response body could be idle
+	ReadWrite time.Duration
+	// Long makes request wait for response header as long as HTTPTimeout.LongTimeout
+	Long bool
+}
+
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a context carrying timeout overrides
+func WithRequestTimeout(ctx context.Context, timeout RequestTimeout) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// requestTimeout merges overrides in ctx with configuration
+func (client *Client) requestTimeout(ctx context.Context, long bool) RequestTimeout {
+	timeout := RequestTimeout{
+		ReadWrite: client.Configuration.readWriteTimeout(),
+		Long:      long,
+	}
+
+	if override, ok := ctx.Value(requestTimeoutKey{}).(RequestTimeout); ok {
+		if override.Total > 0 {
+			timeout.Total = override.Total
+		}
+		if override.ReadWrite > 0 {
+			timeout.ReadWrite = override.ReadWrite
+		}
+		timeout.Long = timeout.Long || override.Long
+	}
+	return timeout
+}
+
+// watchdog cancels a request when its body is idle longer than timeout
+type watchdog struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	fired   bool
+}
+
+// newWatchdog returns a context canceled when watchdog fires or stops, or after total if it's positive.
+// Idle timer starts at the first kick.
+func newWatchdog(ctx context.Context, timeout RequestTimeout) (context.Context, *watchdog) {
+	var cancel context.CancelFunc
+	if timeout.Total > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout.Total)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	return ctx, &watchdog{
+		timeout: timeout.ReadWrite,
+		cancel:  cancel,
+	}
+}
+
+func (w *watchdog) fire() {
+	w.mu.Lock()
+	w.fired = true
+	w.mu.Unlock()
+	w.cancel()
+}
+
+// kick (re)starts idle timer
+func (w *watchdog) kick() {
+	if w.timeout <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.timeout, w.fire)
+	} else {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+// pause stops idle timer till next kick
+func (w *watchdog) pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// stop releases resources of request
+func (w *watchdog) stop() {
+	w.pause()
+	w.cancel()
+}
+
+// translate turns err into ErrorReadWriteTimeout if watchdog fired
+func (w *watchdog) translate(err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err != nil && w.fired {
+		return ErrorReadWriteTimeout
+	}
+	return err
+}
+
+// watchedReader kicks watchdog when reading, watchdog is paused when request body
+// reaches EOF, and stopped when response body is closed
+type watchedReader struct {
+	reader   io.Reader
+	watchdog *watchdog
+	response bool
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.watchdog.kick()
+	}
+	if err == io.EOF && !r.response {
+		// waiting for response header is limited by HeaderTimeout
+		r.watchdog.pause()
+	}
+	if err != nil && err != io.EOF {
+		err = r.watchdog.translate(err)
+	}
+	return n, err
+}
+
+func (r *watchedReader) Close() error {
+	var err error
+	if closer, ok := r.reader.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if r.response {
+		r.watchdog.stop()
+	}
+	return err
+}
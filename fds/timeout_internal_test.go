@@ -0,0 +1,114 @@
+package fds
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withHTTPTimeout(timeout HTTPTimeout) func(*ClientConfiguration) {
+	return func(conf *ClientConfiguration) {
+		conf.HTTPTimeout = timeout
+	}
+}
+
+// stallingHandler writes a byte of body every interval, and stalls after count bytes unless stall is nil
+func stallingHandler(count int, interval time.Duration, stall <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < count; i++ {
+			w.Write([]byte("x"))
+			w.(http.Flusher).Flush()
+			time.Sleep(interval)
+		}
+		if stall == nil {
+			return
+		}
+		select {
+		case <-stall:
+		case <-r.Context().Done():
+		}
+	}
+}
+
+func TestTimeout_ReadWriteStalled(t *testing.T) {
+	stall := make(chan struct{})
+	defer close(stall)
+	client := newTestClient(t, stallingHandler(1, 0, stall),
+		withHTTPTimeout(HTTPTimeout{ReadWriteTimeout: 100 * time.Millisecond}))
+
+	body, err := client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	defer body.Close()
+
+	_, err = ioutil.ReadAll(body)
+	assert.Equal(t, ErrorReadWriteTimeout, err)
+}
+
+func TestTimeout_ReadWriteSlowTransfer(t *testing.T) {
+	client := newTestClient(t, stallingHandler(100, 5*time.Millisecond, nil),
+		withHTTPTimeout(HTTPTimeout{ReadWriteTimeout: 200 * time.Millisecond}))
+
+	body, err := client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	defer body.Close()
+
+	// the whole transfer takes longer than ReadWriteTimeout but is never idle
+	content, err := ioutil.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(content))
+}
+
+func TestTimeout_Header(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}, withHTTPTimeout(HTTPTimeout{
+		HeaderTimeout: 100 * time.Millisecond,
+		LongTimeout:   time.Second,
+	}))
+
+	_, err := client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.NotNil(t, err)
+
+	// CopyObject waits as long as LongTimeout
+	err = client.CopyObject(&CopyObjectRequest{
+		SourceBucketName: "bucket",
+		SourceObjectName: "object",
+		TargetBucketName: "bucket",
+		TargetObjectName: "copy",
+	})
+	assert.Nil(t, err)
+
+	ctx := WithRequestTimeout(context.Background(), RequestTimeout{Long: true})
+	body, err := client.GetObjectWithContext(ctx, &GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	body.Close()
+}
+
+func TestTimeout_RequestOverride(t *testing.T) {
+	stall := make(chan struct{})
+	defer close(stall)
+	client := newTestClient(t, stallingHandler(1, 0, stall),
+		withHTTPTimeout(HTTPTimeout{ReadWriteTimeout: time.Minute}))
+
+	ctx := WithRequestTimeout(context.Background(), RequestTimeout{ReadWrite: 100 * time.Millisecond})
+	body, err := client.GetObjectWithContext(ctx, &GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(body)
+	assert.Equal(t, ErrorReadWriteTimeout, err)
+	body.Close()
+
+	ctx = WithRequestTimeout(context.Background(), RequestTimeout{Total: 100 * time.Millisecond})
+	body, err = client.GetObjectWithContext(ctx, &GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(body)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	body.Close()
+}
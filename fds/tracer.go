@@ -0,0 +1,64 @@
+package fds
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span tag names
+const (
+	TraceTagOperation     = "fds.operation"
+	TraceTagBucket        = "fds.bucket"
+	TraceTagObject        = "fds.object"
+	TraceTagMethod        = "http.method"
+	TraceTagURL           = "http.url"
+	TraceTagStatus        = "http.status_code"
+	TraceTagBytesSent     = "fds.bytes_sent"
+	TraceTagBytesReceived = "fds.bytes_received"
+	TraceTagPeerHost      = "peer.hostname"
+	TraceTagPeerIP        = "peer.ip"
+	TraceTagAttempt       = "fds.attempt"
+	TraceTagPart          = "fds.part"
+	TraceTagError         = "error"
+)
+
+// Tracer starts spans of FDS operations, it's neutral to tracing implementations,
+// so OpenTracing, OpenTelemetry or others could be adapted to it
+type Tracer interface {
+	// StartSpan starts a span named operation as a child of the span carried by ctx
+	// if there is one, and returns a context carrying the new span
+	StartSpan(ctx context.Context, operation string) (context.Context, Span)
+
+	// Inject writes the span context carried by ctx into header of outgoing request
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a traced operation started by Tracer
+type Span interface {
+	SetTag(key string, value interface{})
+	// SetError marks span failed with err
+	SetError(err error)
+	Finish()
+}
+
+// StartSpan starts a span with tracer, a no-op span is returned if tracer is nil
+func StartSpan(ctx context.Context, tracer Tracer, operation string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return tracer.StartSpan(ctx, operation)
+}
+
+// FinishSpan marks span failed if err is not nil and finishes it
+func FinishSpan(span Span, err error) {
+	if err != nil {
+		span.SetError(err)
+	}
+	span.Finish()
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetTag(key string, value interface{}) {}
+func (nopSpan) SetError(err error)                   {}
+func (nopSpan) Finish()                              {}
@@ -0,0 +1,112 @@
+package fds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordSpan struct {
+	id     int
+	parent int
+	name   string
+	tags   map[string]interface{}
+	err    error
+	done   bool
+}
+
+func (s *recordSpan) SetTag(key string, value interface{}) { s.tags[key] = value }
+func (s *recordSpan) SetError(err error)                   { s.err = err }
+func (s *recordSpan) Finish()                              { s.done = true }
+
+type spanKey struct{}
+
+type recordTracer struct {
+	mu    sync.Mutex
+	spans []*recordSpan
+}
+
+func (t *recordTracer) StartSpan(ctx context.Context, operation string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &recordSpan{id: len(t.spans) + 1, name: operation, tags: map[string]interface{}{}}
+	if parent, ok := ctx.Value(spanKey{}).(*recordSpan); ok {
+		span.parent = parent.id
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *recordTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(spanKey{}).(*recordSpan); ok {
+		header.Set("X-Trace-Span", strconv.Itoa(span.id))
+	}
+}
+
+func (t *recordTracer) find(name string) *recordSpan {
+	for _, s := range t.spans {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestTracer_Client(t *testing.T) {
+	var injected string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		injected = r.Header.Get("X-Trace-Span")
+		if r.URL.Path == "/bucket/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	tracer := &recordTracer{}
+	conf, _ := NewClientConfiguration(strings.TrimPrefix(server.URL, "http://"))
+	conf.EnableHTTPS = false
+	conf.Tracer = tracer
+	client := New("ak", "sk", conf)
+
+	ctx, root := tracer.StartSpan(context.Background(), "root")
+	reader, err := client.GetObjectWithContext(ctx, &GetObjectRequest{BucketName: "bucket", ObjectName: "object"})
+	assert.Nil(t, err)
+	reader.Close()
+	root.Finish()
+
+	span := tracer.find("fds.GetObject")
+	assert.NotNil(t, span)
+	assert.Equal(t, 1, span.parent)
+	assert.True(t, span.done)
+	assert.Equal(t, "bucket", span.tags[TraceTagBucket])
+	assert.Equal(t, "object", span.tags[TraceTagObject])
+	assert.Equal(t, http.StatusOK, span.tags[TraceTagStatus])
+	assert.Equal(t, strconv.Itoa(span.id), injected)
+
+	dial := tracer.find("fds.dial")
+	assert.NotNil(t, dial)
+	assert.Equal(t, span.id, dial.parent)
+	attempt := tracer.find("fds.dial.attempt")
+	assert.NotNil(t, attempt)
+	assert.Equal(t, dial.id, attempt.parent)
+	assert.Equal(t, "127.0.0.1", attempt.tags[TraceTagPeerIP])
+
+	_, err = client.GetObject(&GetObjectRequest{BucketName: "bucket", ObjectName: "missing"})
+	assert.NotNil(t, err)
+	var failed *recordSpan
+	for _, s := range tracer.spans {
+		if s.name == "fds.GetObject" {
+			failed = s
+		}
+	}
+	assert.Equal(t, err, failed.err)
+}
@@ -0,0 +1,53 @@
+package fds
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaxIdleConns is the default value of ClientConfiguration.MaxIdleConns
+const DefaultMaxIdleConns = 100
+
+// newTransport builds http.Transport of Client from conf
+func newTransport(conf *ClientConfiguration) *http.Transport {
+	dialer := net.Dialer{
+		Timeout:   conf.HTTPTimeout.ConnectTimeout,
+		KeepAlive: conf.HTTPTimeout.KeepAliveTimeout,
+	}
+
+	// Ref: net/http.DefaultTransport
+	transport := &http.Transport{
+		Proxy:                 conf.proxy(),
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       conf.TLSConfig,
+		ForceAttemptHTTP2:     !conf.DisableHTTP2,
+		MaxIdleConns:          conf.MaxIdleConns,
+		MaxIdleConnsPerHost:   int(conf.MaxConnection),
+		MaxConnsPerHost:       conf.MaxConnsPerHost,
+		IdleConnTimeout:       conf.HTTPTimeout.IdleConnTimeout,
+		TLSHandshakeTimeout:   conf.HTTPTimeout.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: conf.HTTPTimeout.HeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if !conf.DisableCSLB {
+		transport.DialContext = (&cslbDialer{
+			Dialer:       dialer,
+			maxNodeCount: conf.MaxConnection,
+			lbs:          sync.Map{},
+			metrics:      conf.Metrics,
+			tracer:       conf.Tracer,
+		}).DialContext
+	}
+	if conf.HTTPKeepAliveTimeoutMs > 0 {
+		transport.IdleConnTimeout = time.Duration(conf.HTTPKeepAliveTimeoutMs) * time.Millisecond
+	}
+	if conf.DisableHTTP2 {
+		// a non-nil empty map disables HTTP/2 even if TLSClientConfig is set
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport
+}
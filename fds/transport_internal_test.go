@@ -0,0 +1,105 @@
+package fds
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingRoundTripper struct {
+	count int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.count, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTransport_Default(t *testing.T) {
+	conf, _ := NewClientConfiguration("cnbj0.fds.api.xiaomi.com")
+	client := New("ak", "sk", conf)
+
+	assert.Equal(t, DefaultMaxIdleConns, client.transport.MaxIdleConns)
+	assert.Equal(t, int(conf.MaxConnection), client.transport.MaxIdleConnsPerHost)
+	assert.True(t, client.transport.ForceAttemptHTTP2)
+	assert.Equal(t, conf.HTTPTimeout.HeaderTimeout, client.transport.ResponseHeaderTimeout)
+}
+
+func TestTransport_TLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	conf, _ := NewClientConfiguration(strings.TrimPrefix(server.URL, "https://"))
+	conf.DisableCSLB = true
+	client := New("ak", "sk", conf)
+	_, err := client.GetObjectMetadata("bucket", "object")
+	assert.NotNil(t, err, "certificate of test server is not trusted by default")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	conf.TLSConfig = &tls.Config{RootCAs: pool}
+	client = New("ak", "sk", conf)
+	_, err = client.GetObjectMetadata("bucket", "object")
+	assert.Nil(t, err)
+}
+
+func TestTransport_Proxy(t *testing.T) {
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		// requests to proxy have absolute URL
+		assert.Equal(t, "files.fds.api.xiaomi.com", r.URL.Host)
+		w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	conf, _ := NewClientConfiguration("files.fds.api.xiaomi.com")
+	conf.EnableHTTPS = false
+	conf.ProxyURL, _ = url.Parse(proxy.URL)
+	client := New("ak", "sk", conf)
+
+	_, err := client.GetObjectMetadata("bucket", "object")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+}
+
+func TestTransport_Injection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	conf, _ := NewClientConfiguration(strings.TrimPrefix(server.URL, "http://"))
+	conf.EnableHTTPS = false
+
+	rt := &countingRoundTripper{}
+	conf.Transport = rt
+	client := New("ak", "sk", conf)
+	assert.Nil(t, client.transport)
+	_, err := client.GetObjectMetadata("bucket", "object")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rt.count))
+
+	// HTTPClient takes precedence over Transport
+	httpRT := &countingRoundTripper{}
+	conf.HTTPClient = &http.Client{Transport: httpRT}
+	client = New("ak", "sk", conf)
+	err = client.CopyObject(&CopyObjectRequest{
+		SourceBucketName: "bucket",
+		SourceObjectName: "object",
+		TargetBucketName: "bucket",
+		TargetObjectName: "copy",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rt.count))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&httpRT.count))
+}
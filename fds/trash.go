@@ -0,0 +1,232 @@
+package fds
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// TrashBucketName is the bucket holding objects deleted with put2trash, an object
+// in trash is named as bucketName/objectName
+const TrashBucketName = "trash"
+
+// TrashObject is an object in trash, LastModified of ObjectSummary is when it's deleted
+type TrashObject struct {
+	ObjectSummary
+	// BucketName and ObjectName are where the object was deleted from
+	BucketName string
+	ObjectName string
+}
+
+// TrashName is name of the object in TrashBucketName
+func (o *TrashObject) TrashName() string {
+	return o.ObjectSummary.ObjectName
+}
+
+// ListTrashRequest is input of ListTrash
+type ListTrashRequest struct {
+	BucketName string
+	Prefix     string
+	// MaxKeys limits objects of a batch, DefaultListObjectsMaxKeys is used if it's 0
+	MaxKeys int
+}
+
+// TrashListing is a batch of trashed objects of a bucket
+type TrashListing struct {
+	BucketName string
+	Prefix     string
+	Objects    []TrashObject
+	Truncated  bool
+
+	listing *ObjectListing
+}
+
+// ListTrash lists trashed objects of bucket with prefix, use ListTrashNextBatch if it's truncated
+func (client *Client) ListTrash(request *ListTrashRequest) (*TrashListing, error) {
+	return client.ListTrashWithContext(context.Background(), request)
+}
+
+// ListTrashWithContext lists trashed objects of bucket with prefix with context controlling
+func (client *Client) ListTrashWithContext(ctx context.Context, request *ListTrashRequest) (*TrashListing, error) {
+	if request.BucketName == "" {
+		return nil, ErrorTrashBucketName
+	}
+	maxKeys := request.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = DefaultListObjectsMaxKeys
+	}
+
+	listing, err := client.ListObjectsWithContext(ctx, &ListObjectsRequest{
+		BucketName: TrashBucketName,
+		Prefix:     request.BucketName + "/" + request.Prefix,
+		MaxKeys:    maxKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newTrashListing(request.BucketName, request.Prefix, listing), nil
+}
+
+// ListTrashNextBatch lists next batch of ListTrash
+func (client *Client) ListTrashNextBatch(previous *TrashListing) (*TrashListing, error) {
+	return client.ListTrashNextBatchWithContext(context.Background(), previous)
+}
+
+// ListTrashNextBatchWithContext lists next batch of ListTrash with context controlling
+func (client *Client) ListTrashNextBatchWithContext(ctx context.Context, previous *TrashListing) (*TrashListing, error) {
+	listing, err := client.ListObjectsNextBatchWithContext(ctx, previous.listing)
+	if err != nil {
+		return nil, err
+	}
+	return newTrashListing(previous.BucketName, previous.Prefix, listing), nil
+}
+
+func newTrashListing(bucketName, prefix string, listing *ObjectListing) *TrashListing {
+	result := &TrashListing{
+		BucketName: bucketName,
+		Prefix:     prefix,
+		Truncated:  listing.Truncated,
+		listing:    listing,
+	}
+	for _, summary := range listing.ObjectSummaries {
+		result.Objects = append(result.Objects, TrashObject{
+			ObjectSummary: summary,
+			BucketName:    bucketName,
+			ObjectName:    strings.TrimPrefix(summary.ObjectName, bucketName+"/"),
+		})
+	}
+	return result
+}
+
+// GetTrashObjectMetadata returns metadata of objectName of bucketName in trash
+func (client *Client) GetTrashObjectMetadata(bucketName, objectName string) (*ObjectMetadata, error) {
+	return client.GetTrashObjectMetadataWithContext(context.Background(), bucketName, objectName)
+}
+
+// GetTrashObjectMetadataWithContext returns metadata of object in trash with context controlling
+func (client *Client) GetTrashObjectMetadataWithContext(ctx context.Context, bucketName, objectName string) (*ObjectMetadata, error) {
+	return client.GetObjectMetadataWithContext(ctx, TrashBucketName, bucketName+"/"+objectName)
+}
+
+// TrashFilter selects trashed objects of a bucket, zero DeletedAfter or DeletedBefore means no limit
+type TrashFilter struct {
+	BucketName    string
+	Prefix        string
+	DeletedAfter  time.Time
+	DeletedBefore time.Time
+}
+
+func (filter *TrashFilter) match(o *TrashObject) bool {
+	if !filter.DeletedAfter.IsZero() && o.LastModified.Before(filter.DeletedAfter) {
+		return false
+	}
+	if !filter.DeletedBefore.IsZero() && !o.LastModified.Before(filter.DeletedBefore) {
+		return false
+	}
+	return true
+}
+
+// walkTrash calls fn with matched objects batch by batch
+func (client *Client) walkTrash(ctx context.Context, filter *TrashFilter, fn func([]TrashObject) error) error {
+	listing, err := client.ListTrashWithContext(ctx, &ListTrashRequest{BucketName: filter.BucketName, Prefix: filter.Prefix})
+	for {
+		if err != nil {
+			return err
+		}
+		var matched []TrashObject
+		for i := range listing.Objects {
+			if filter.match(&listing.Objects[i]) {
+				matched = append(matched, listing.Objects[i])
+			}
+		}
+		if len(matched) > 0 {
+			if err := fn(matched); err != nil {
+				return err
+			}
+		}
+		if !listing.Truncated {
+			return nil
+		}
+		listing, err = client.ListTrashNextBatchWithContext(ctx, listing)
+	}
+}
+
+// TrashResult is result of RestoreTrash and PurgeTrash, names are object names in the original bucket
+type TrashResult struct {
+	Matched   int
+	Succeeded []string
+	Failed    map[string]error
+}
+
+// RestoreTrashRequest is input of RestoreTrash
+type RestoreTrashRequest struct {
+	TrashFilter
+	// DryRun only counts matched objects
+	DryRun bool
+}
+
+// RestoreTrash restores matched objects to their bucket. An object failed to be restored,
+// e.g. a newer one with the same name exists, is recorded in Failed and doesn't stop others.
+func (client *Client) RestoreTrash(request *RestoreTrashRequest) (*TrashResult, error) {
+	return client.RestoreTrashWithContext(context.Background(), request)
+}
+
+// RestoreTrashWithContext restores matched objects to their bucket with context controlling
+func (client *Client) RestoreTrashWithContext(ctx context.Context, request *RestoreTrashRequest) (*TrashResult, error) {
+	result := &TrashResult{Failed: map[string]error{}}
+	err := client.walkTrash(ctx, &request.TrashFilter, func(objects []TrashObject) error {
+		result.Matched += len(objects)
+		if request.DryRun {
+			return nil
+		}
+		for _, o := range objects {
+			if err := client.RestoreObjectWithContext(ctx, o.BucketName, o.ObjectName); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				result.Failed[o.ObjectName] = err
+				continue
+			}
+			result.Succeeded = append(result.Succeeded, o.ObjectName)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// PurgeTrashRequest is input of PurgeTrash
+type PurgeTrashRequest struct {
+	TrashFilter
+	// DryRun only counts matched objects
+	DryRun bool
+}
+
+// PurgeTrash deletes matched objects from trash permanently, they can't be restored anymore.
+// Objects are deleted one by one, so an object failed to be deleted is recorded in Failed and
+// doesn't stop others.
+func (client *Client) PurgeTrash(request *PurgeTrashRequest) (*TrashResult, error) {
+	return client.PurgeTrashWithContext(context.Background(), request)
+}
+
+// PurgeTrashWithContext deletes matched objects from trash permanently with context controlling
+func (client *Client) PurgeTrashWithContext(ctx context.Context, request *PurgeTrashRequest) (*TrashResult, error) {
+	result := &TrashResult{Failed: map[string]error{}}
+	err := client.walkTrash(ctx, &request.TrashFilter, func(objects []TrashObject) error {
+		result.Matched += len(objects)
+		if request.DryRun {
+			return nil
+		}
+		for _, o := range objects {
+			if err := client.DeleteObjectWithContext(ctx, TrashBucketName, o.TrashName()); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				result.Failed[o.ObjectName] = err
+				continue
+			}
+			result.Succeeded = append(result.Succeeded, o.ObjectName)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,134 @@
+package fds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trashServer keeps trash of bucket in memory, listing returns one object per batch
+type trashServer struct {
+	sync.Mutex
+	trash    map[string]time.Time
+	restored []string
+	deleted  []string
+}
+
+func (s *trashServer) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+		query := r.URL.Query()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/trash":
+			var names []string
+			for name := range s.trash {
+				if strings.HasPrefix(name, query.Get("prefix")) && name > query.Get("marker") {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			listing := ObjectListing{BucketName: "trash", Prefix: query.Get("prefix")}
+			if len(names) > 0 {
+				listing.ObjectSummaries = []ObjectSummary{{ObjectName: names[0], LastModified: s.trash[names[0]]}}
+				listing.Truncated = len(names) > 1
+				listing.NextMarker = names[0]
+			}
+			json.NewEncoder(w).Encode(listing)
+		case r.Method == http.MethodPut && query["restore"] != nil:
+			name := strings.TrimPrefix(r.URL.Path, "/")
+			if name == "bucket/conflict" {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			delete(s.trash, name)
+			s.restored = append(s.restored, name)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/trash/"):
+			name := strings.TrimPrefix(r.URL.Path, "/trash/")
+			if name == "bucket/a/2" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			delete(s.trash, name)
+			s.deleted = append(s.deleted, name)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func newTrashServer(t *testing.T) (*Client, *trashServer) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	s := &trashServer{trash: map[string]time.Time{
+		"bucket/a/1":      day.Add(-48 * time.Hour),
+		"bucket/a/2":      day.Add(time.Hour),
+		"bucket/a/3":      day.Add(2 * time.Hour),
+		"bucket/b/1":      day.Add(time.Hour),
+		"bucket/conflict": day.Add(time.Hour),
+		"other/a/1":       day,
+	}}
+	return newTestClient(t, s.handle(t)), s
+}
+
+func TestListTrash(t *testing.T) {
+	client, _ := newTrashServer(t)
+
+	_, err := client.ListTrash(&ListTrashRequest{})
+	assert.Equal(t, ErrorTrashBucketName, err)
+
+	listing, err := client.ListTrash(&ListTrashRequest{BucketName: "bucket", Prefix: "a/"})
+	var names []string
+	for {
+		assert.Nil(t, err)
+		for _, o := range listing.Objects {
+			assert.Equal(t, "bucket", o.BucketName)
+			assert.Equal(t, "bucket/"+o.ObjectName, o.TrashName())
+			names = append(names, o.ObjectName)
+		}
+		if !listing.Truncated {
+			break
+		}
+		listing, err = client.ListTrashNextBatch(listing)
+	}
+	assert.Equal(t, []string{"a/1", "a/2", "a/3"}, names)
+}
+
+func TestRestoreTrash(t *testing.T) {
+	client, s := newTrashServer(t)
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	filter := TrashFilter{BucketName: "bucket", DeletedAfter: day, DeletedBefore: day.Add(2 * time.Hour)}
+
+	result, err := client.RestoreTrash(&RestoreTrashRequest{TrashFilter: filter, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Matched)
+	assert.Empty(t, s.restored)
+
+	result, err = client.RestoreTrash(&RestoreTrashRequest{TrashFilter: filter})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Matched)
+	assert.Equal(t, []string{"a/2", "b/1"}, result.Succeeded)
+	assert.Equal(t, 1, len(result.Failed))
+	assert.NotNil(t, result.Failed["conflict"])
+	assert.Equal(t, []string{"bucket/a/2", "bucket/b/1"}, s.restored)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	client, s := newTrashServer(t)
+
+	result, err := client.PurgeTrash(&PurgeTrashRequest{TrashFilter: TrashFilter{BucketName: "bucket", Prefix: "a/"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Matched)
+	assert.Equal(t, []string{"a/1", "a/3"}, result.Succeeded)
+	assert.Equal(t, 1, len(result.Failed))
+	assert.NotNil(t, result.Failed["a/2"])
+	assert.Equal(t, []string{"bucket/a/1", "bucket/a/3"}, s.deleted)
+	assert.Equal(t, 4, len(s.trash), fmt.Sprint(s.trash))
+}
@@ -7,7 +7,7 @@ require (
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
-	golang.org/x/time v0.3.0 // indirect
+	golang.org/x/time v0.3.0
 )
 
 go 1.16